@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
+	"path"
 	"strings"
 	"time"
 
@@ -17,6 +19,7 @@ type Client struct {
 	Service Service
 	bucket  string
 	prefix  string
+	env     string
 	ctx     context.Context //nolint:containedctx
 	log     zerolog.Logger
 }
@@ -27,6 +30,14 @@ type EncryptedConfig struct {
 	Data        string `json:"data"`
 }
 
+// SecretStream is an encrypted config uploaded from a reader, so large
+// bundles aren't buffered.
+type SecretStream struct {
+	Environment string
+	Name        string
+	Data        io.Reader
+}
+
 type SecretObject struct {
 	Name      string    `json:"name"`
 	Location  string    `json:"location"`
@@ -47,31 +58,77 @@ func (o SecretObject) BaseName() string {
 	return name
 }
 
+// IsKey reports whether the object is a published key rather than a config.
+func (o SecretObject) IsKey() bool {
+	return strings.HasSuffix(o.Name, keySuffix) || strings.HasSuffix(o.Name, signingKeySuffix) ||
+		strings.HasSuffix(o.Name, "/"+keyringFile)
+}
+
+// root is the path of the environment of the client in the bucket.
+func (c *Client) root() string {
+	return EnvironmentRoot(c.prefix, c.env)
+}
+
+func (c *Client) keysPath() string {
+	return path.Join(c.root(), keysDir)
+}
+
 func (c *Client) Init(ctx context.Context, bucket string, cfg Configuration) error {
 	return c.Service.Init(ctx, bucket, cfg)
 }
 
 func (c *Client) UploadSecrets(req EncryptedConfig) error {
 	c.log.Trace().Msgf("uploading to %s name: %s", c.bucket, req.Name)
-	return c.Service.Upload(c.ctx, req, c.bucket)
+	return c.Service.Upload(c.ctx, req, c.bucket, c.root())
+}
+
+func (c *Client) UploadSecretStream(req SecretStream) error {
+	c.log.Trace().Msgf("streaming to %s name: %s", c.bucket, req.Name)
+	return c.Service.UploadStream(c.ctx, req, c.bucket, c.root())
 }
 
 type FetchSecretRequest struct {
 	Environment string `json:"environment"`
 	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
 }
 type FetchSecretResponse struct {
 	Data string `json:"data"`
 }
 
 func (c *Client) FetchSecrets(req FetchSecretRequest) ([]byte, error) {
-	data, err := c.Service.FetchConfig(c.ctx, c.bucket, req)
+	data, err := c.Service.FetchConfig(c.ctx, c.bucket, c.root(), req)
 	if err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
+func (c *Client) FetchSecretStream(req FetchSecretRequest) (io.ReadCloser, error) {
+	return c.Service.FetchConfigStream(c.ctx, c.bucket, c.root(), req)
+}
+
+type DeleteSecretRequest struct {
+	Environment string `json:"environment"`
+	Name        string `json:"name"`
+}
+
+type RenameSecretRequest struct {
+	Environment string `json:"environment"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+}
+
+func (c *Client) DeleteSecret(req DeleteSecretRequest) error {
+	c.log.Debug().Msgf("deleting %s from %s", req.Name, c.bucket)
+	return c.Service.DeleteConfig(c.ctx, c.bucket, c.root(), req.Name)
+}
+
+func (c *Client) RenameSecret(req RenameSecretRequest) error {
+	c.log.Debug().Msgf("renaming %s to %s in %s", req.From, req.To, c.bucket)
+	return c.Service.RenameConfig(c.ctx, c.bucket, c.root(), req.From, req.To)
+}
+
 type Recipient struct {
 	PublicKey string `json:"public_key"`
 }
@@ -90,7 +147,7 @@ func (pk OrgPublicKeys) RecipientList() []string {
 
 func (c *Client) GetOrgPublicKeys(env string) (OrgPublicKeys, error) {
 	c.log.Debug().Msgf("fetching public keys for env: %s", env)
-	keys, err := c.Service.GetOrgPublicKeys(c.ctx, env, c.bucket, c.prefix+"/keys")
+	keys, err := c.Service.GetOrgPublicKeys(c.ctx, env, c.bucket, c.keysPath())
 	if err != nil || len(keys) == 0 {
 		return OrgPublicKeys{}, err
 	}
@@ -101,6 +158,86 @@ func (c *Client) GetOrgPublicKeys(env string) (OrgPublicKeys, error) {
 	return OrgPublicKeys{Recipients: recps}, nil
 }
 
+// Signer is the signing public key a team member publishes at init.
+type Signer struct {
+	UserID    string `json:"user_id"`
+	PublicKey string `json:"public_key"`
+}
+
+type SignersResponse struct {
+	Signers []Signer `json:"signers"`
+}
+
+func (c *Client) GetSigners(env string) ([]Signer, error) {
+	c.log.Debug().Msgf("fetching signing keys for env: %s", env)
+	keys, err := c.Service.GetSigningKeys(c.ctx, c.bucket, c.keysPath())
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]Signer, 0, len(keys))
+	for id, key := range keys {
+		signers = append(signers, Signer{UserID: id, PublicKey: key})
+	}
+	return signers, nil
+}
+
+// UserKeys are the keys a user published at init or that were added for
+// them.
+type UserKeys struct {
+	UserID     string    `json:"user_id"`
+	PublicKey  string    `json:"public_key"`
+	SigningKey string    `json:"signing_key,omitempty"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+func (c *Client) GetKeyring(_ string) ([]byte, error) {
+	return c.Service.ReadKeyring(c.ctx, c.bucket, c.keysPath())
+}
+
+func (c *Client) UploadKeyring(_ string, data []byte) error {
+	c.log.Debug().Msgf("uploading keyring to %s", c.bucket)
+	return c.Service.WriteKeyring(c.ctx, c.bucket, c.keysPath(), data)
+}
+
+func (c *Client) GetUserKeys(_, userID string) (UserKeys, error) {
+	return c.Service.ReadUserKeys(c.ctx, c.bucket, c.keysPath(), userID)
+}
+
+func (c *Client) ListKeys(_ string) ([]UserKeys, error) {
+	return c.Service.ListUserKeys(c.ctx, c.bucket, c.keysPath())
+}
+
+func (c *Client) AddKey(_ string, keys UserKeys) error {
+	c.log.Debug().Msgf("adding keys of %s", keys.UserID)
+	return c.Service.WriteUserKeys(c.ctx, c.bucket, c.keysPath(), keys)
+}
+
+func (c *Client) RemoveKey(_, userID string) error {
+	c.log.Debug().Msgf("removing keys of %s", userID)
+	return c.Service.DeleteUserKeys(c.ctx, c.bucket, c.keysPath(), userID)
+}
+
+// EnvironmentRequest creates an environment, the server sets it up like
+// the From environment.
+type EnvironmentRequest struct {
+	Environment string `json:"environment"`
+	From        string `json:"from,omitempty"`
+}
+
+type EnvironmentsResponse struct {
+	Environments []string `json:"environments"`
+}
+
+func (c *Client) ListEnvironments() ([]string, error) {
+	return c.Service.ListEnvironments(c.ctx, c.bucket, c.prefix)
+}
+
+func (c *Client) CreateEnvironment(req EnvironmentRequest) error {
+	c.log.Debug().Msgf("creating environment %s in %s", req.Environment, c.bucket)
+	md := config.Metadata{Bucket: c.bucket, Location: c.prefix, Environment: req.Environment, CreatedAt: time.Now()}
+	return c.Service.CreateEnvironment(c.ctx, c.bucket, md)
+}
+
 type SecretListConfig struct {
 	Environment string `json:"environment"`
 }
@@ -108,14 +245,17 @@ type SecretListResponse struct {
 	Secrets []SecretObject `json:"secrets"`
 }
 
+// GetSecretList lists the configs of the environment, named relative to its
+// root like the server does.
 func (c *Client) GetSecretList(_ SecretListConfig) ([]SecretObject, error) {
-	resp, err := c.Service.ListObject(c.ctx, c.bucket, c.prefix)
+	resp, err := c.Service.ListObject(c.ctx, c.bucket, path.Join(c.root(), secretsDir)+"/")
 	if err != nil {
 		return nil, err
 	}
 	objs := make([]SecretObject, 0)
 	for _, obj := range resp {
-		o := SecretObject{Name: obj.Name, CreatedAt: obj.Created, UpdatedAt: obj.Updated, Location: fmt.Sprintf("%s/%s", obj.Bucket, obj.Name)}
+		name := strings.TrimPrefix(obj.Name, c.root()+"/")
+		o := SecretObject{Name: name, CreatedAt: obj.Created, UpdatedAt: obj.Updated, Location: fmt.Sprintf("%s/%s", obj.Bucket, obj.Name)}
 		objs = append(objs, o)
 	}
 	return objs, nil
@@ -160,7 +300,11 @@ func New(ctx context.Context, cfg config.Client) (*Client, error) {
 		Service: Service{store: st},
 		bucket:  cfg.BucketName(),
 		prefix:  cfg.StoragePrefix,
+		env:     cfg.Environment,
 		log:     log.With().Str("bucket", cfg.BucketName()).Str("prefix", cfg.StoragePrefix).Str("provider", cfg.Provider).Logger(),
 	}
+	if legacy, err := cli.Service.IsLegacyLayout(ctx, cli.bucket); err == nil && legacy {
+		cli.log.Warn().Msgf("bucket uses the legacy layout, move it with `dolores --env %s migrate`", cfg.Environment)
+	}
 	return cli, nil
 }
@@ -6,16 +6,32 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scalescape/dolores/config"
 	"github.com/scalescape/dolores/store/cloud"
 )
 
-var ErrInvalidPublicKeys = errors.New("invalid public keys")
+var (
+	ErrInvalidPublicKeys = errors.New("invalid public keys")
+	ErrKeyringNotFound   = errors.New("keyring not found")
+	ErrEnvironmentExists = errors.New("environment already exists")
+	ErrConfigNotFound    = errors.New("config not found")
+	ErrConfigExists      = errors.New("config already exists")
+)
 
-const metadataFile = "dolores.md"
+const (
+	metadataFile     = "dolores.md"
+	keysDir          = "keys"
+	secretsDir       = "secrets"
+	keySuffix        = ".key"
+	signingKeySuffix = ".pub"
+	keyringFile      = "keyring.json"
+)
 
 type Service struct {
 	store cloudStore
@@ -26,83 +42,213 @@ type cloudStore interface {
 	ReadObject(ctx context.Context, bucketName, fileName string) ([]byte, error)
 	ListObject(ctx context.Context, bucketName, path string) ([]cloud.Object, error)
 	ExistsObject(ctx context.Context, bucketName, fileName string) (bool, error)
+	WriteObjectStream(ctx context.Context, bucketName, fileName string, r io.Reader) error
+	ReadObjectStream(ctx context.Context, bucketName, fileName string) (io.ReadCloser, error)
+	DeleteObject(ctx context.Context, bucketName, fileName string) error
+	CopyObject(ctx context.Context, bucketName, src, dst string) error
+	VersioningEnabled(ctx context.Context, bucketName string) (bool, error)
+	ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cloud.ObjectVersion, error)
+	ReadObjectVersionStream(ctx context.Context, bucketName, fileName, version string) (io.ReadCloser, error)
+	DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error
 }
 
 type Configuration struct {
 	Metadata  config.Metadata
 	PublicKey string
-	UserID    string
+	// SigningKey is published next to the public key, readers verify the
+	// configs uploaded by the user with it.
+	SigningKey string
+	UserID     string
 }
 
 func (s Service) Init(ctx context.Context, bucket string, cfg Configuration) error {
 	// saving metadata and append key to google cloud storage
+	root := EnvironmentRoot(cfg.Metadata.Location, cfg.Metadata.Environment)
 	if cfg.PublicKey != "" {
-		pubKey := fmt.Sprintf("%s/keys/%s.key", cfg.Metadata.Location, cfg.UserID)
+		pubKey := path.Join(root, keysDir, cfg.UserID+keySuffix)
 		if err := s.uploadPubKey(ctx, bucket, pubKey, cfg.PublicKey); err != nil {
 			return fmt.Errorf("error writing public key: %w", err)
 		}
 		log.Info().Msgf("uploaded public key successfully.")
 	}
-	exists, err := s.store.ExistsObject(ctx, bucket, metadataFile)
-	if err != nil {
-		return err
+	if cfg.SigningKey != "" {
+		signKey := path.Join(root, keysDir, cfg.UserID+signingKeySuffix)
+		if err := s.uploadPubKey(ctx, bucket, signKey, cfg.SigningKey); err != nil {
+			return fmt.Errorf("error writing signing key: %w", err)
+		}
+		log.Info().Msgf("uploaded signing key successfully.")
 	}
-	if exists {
+	err := s.CreateEnvironment(ctx, bucket, cfg.Metadata)
+	if errors.Is(err, ErrEnvironmentExists) {
 		log.Info().Msgf("metadata already configured in remote")
 		return nil
-	}
-	if err := s.saveObject(ctx, bucket, metadataFile, cfg.Metadata); err != nil {
+	} else if err != nil {
 		log.Error().Msgf("error writing metadta: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (s Service) Upload(ctx context.Context, req EncryptedConfig, bucket string) error {
-	prefix, err := s.getObjectPrefix(ctx, req.Environment, bucket)
+func (s Service) Upload(ctx context.Context, req EncryptedConfig, bucket, root string) error {
+	fileName := configPath(root, req.Name)
+	data, err := base64.StdEncoding.DecodeString(req.Data)
 	if err != nil {
 		return err
 	}
-	fileName := req.Name
-	if prefix != "" {
-		fileName = fmt.Sprintf("%s/%s", prefix, fileName)
-	}
-	data, err := base64.StdEncoding.DecodeString(req.Data)
-	if err != nil {
+	if err := s.keepHistory(ctx, bucket, root, req.Name); err != nil {
 		return err
 	}
 	return s.store.WriteToObject(ctx, bucket, fileName, data)
 }
 
 func (s Service) GetOrgPublicKeys(ctx context.Context, env, bucketName, path string) ([]string, error) {
-	pubKey := os.Getenv("DOLORES_PUBLIC_KEY")
-	if pubKey != "" {
-		return []string{pubKey}, nil
+	resp, err := s.ListObject(ctx, bucketName, path)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
 	}
+	keys := make([]string, 0, len(resp))
+	for _, obj := range resp {
+		if !strings.HasSuffix(obj.Name, keySuffix) {
+			continue
+		}
+		key, err := s.store.ReadObject(ctx, bucketName, obj.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", obj, err)
+		}
+		keys = append(keys, string(key))
+	}
+	return keys, nil
+}
+
+// GetSigningKeys returns the signing public keys under path by user id.
+func (s Service) GetSigningKeys(ctx context.Context, bucketName, path string) (map[string]string, error) {
 	resp, err := s.ListObject(ctx, bucketName, path)
 	if err != nil {
 		return nil, fmt.Errorf("error listing objects: %w", err)
 	}
-	keys := make([]string, len(resp))
-	for i, obj := range resp {
+	keys := make(map[string]string)
+	for _, obj := range resp {
+		if !strings.HasSuffix(obj.Name, signingKeySuffix) {
+			continue
+		}
 		key, err := s.store.ReadObject(ctx, bucketName, obj.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read object %s: %w", obj, err)
 		}
-		keys[i] = string(key)
+		id := strings.TrimSuffix(filepath.Base(obj.Name), signingKeySuffix)
+		keys[id] = strings.TrimSpace(string(key))
 	}
 	return keys, nil
 }
 
-func (s Service) FetchConfig(ctx context.Context, bucket string, req FetchSecretRequest) ([]byte, error) {
-	fileName := req.Name
-	prefix, err := s.getObjectPrefix(ctx, req.Environment, bucket)
+// ReadKeyring returns the keyring manifest stored in the keys path.
+func (s Service) ReadKeyring(ctx context.Context, bucket, path string) ([]byte, error) {
+	fname := fmt.Sprintf("%s/%s", path, keyringFile)
+	exists, err := s.store.ExistsObject(ctx, bucket, fname)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to check keyring: %w", err)
+	}
+	if !exists {
+		return nil, ErrKeyringNotFound
+	}
+	return s.store.ReadObject(ctx, bucket, fname)
+}
+
+func (s Service) WriteKeyring(ctx context.Context, bucket, path string, data []byte) error {
+	return s.store.WriteToObject(ctx, bucket, fmt.Sprintf("%s/%s", path, keyringFile), data)
+}
+
+// ReadUserKeys returns the keys a user published, approved or not. Users
+// added by someone else may have no signing key.
+func (s Service) ReadUserKeys(ctx context.Context, bucket, path, userID string) (UserKeys, error) {
+	keys := UserKeys{UserID: userID}
+	pub, err := s.store.ReadObject(ctx, bucket, fmt.Sprintf("%s/%s%s", path, userID, keySuffix))
+	if err != nil {
+		return UserKeys{}, fmt.Errorf("failed to read public key of %s: %w", userID, err)
+	}
+	keys.PublicKey = strings.TrimSpace(string(pub))
+	signKey := fmt.Sprintf("%s/%s%s", path, userID, signingKeySuffix)
+	if exists, err := s.store.ExistsObject(ctx, bucket, signKey); err != nil || !exists {
+		return keys, err
+	}
+	sign, err := s.store.ReadObject(ctx, bucket, signKey)
+	if err != nil {
+		return UserKeys{}, fmt.Errorf("failed to read signing key of %s: %w", userID, err)
+	}
+	keys.SigningKey = strings.TrimSpace(string(sign))
+	return keys, nil
+}
+
+// ListUserKeys returns the public keys published under path.
+func (s Service) ListUserKeys(ctx context.Context, bucket, path string) ([]UserKeys, error) {
+	resp, err := s.ListObject(ctx, bucket, path)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+	keys := make([]UserKeys, 0, len(resp))
+	for _, obj := range resp {
+		if !strings.HasSuffix(obj.Name, keySuffix) {
+			continue
+		}
+		key, err := s.store.ReadObject(ctx, bucket, obj.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", obj, err)
+		}
+		id := strings.TrimSuffix(filepath.Base(obj.Name), keySuffix)
+		keys = append(keys, UserKeys{UserID: id, PublicKey: strings.TrimSpace(string(key)), AddedAt: obj.Created})
+	}
+	return keys, nil
+}
+
+func (s Service) WriteUserKeys(ctx context.Context, bucket, path string, keys UserKeys) error {
+	if err := s.uploadPubKey(ctx, bucket, fmt.Sprintf("%s/%s%s", path, keys.UserID, keySuffix), keys.PublicKey); err != nil {
+		return fmt.Errorf("error writing public key: %w", err)
+	}
+	if keys.SigningKey == "" {
+		return nil
+	}
+	if err := s.uploadPubKey(ctx, bucket, fmt.Sprintf("%s/%s%s", path, keys.UserID, signingKeySuffix), keys.SigningKey); err != nil {
+		return fmt.Errorf("error writing signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserKeys removes the keys the user published.
+func (s Service) DeleteUserKeys(ctx context.Context, bucket, path, userID string) error {
+	for _, suffix := range []string{keySuffix, signingKeySuffix} {
+		fname := fmt.Sprintf("%s/%s%s", path, userID, suffix)
+		exists, err := s.store.ExistsObject(ctx, bucket, fname)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", fname, err)
+		}
+		if !exists {
+			continue
+		}
+		if err := s.store.DeleteObject(ctx, bucket, fname); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", fname, err)
+		}
 	}
-	if prefix != "" {
-		fileName = fmt.Sprintf("%s/%s", prefix, fileName)
+	return nil
+}
+
+func (s Service) UploadStream(ctx context.Context, req SecretStream, bucket, root string) error {
+	fileName := configPath(root, req.Name)
+	if err := s.keepHistory(ctx, bucket, root, req.Name); err != nil {
+		return err
 	}
+	return s.store.WriteObjectStream(ctx, bucket, fileName, req.Data)
+}
+
+func (s Service) FetchConfig(ctx context.Context, bucket, root string, req FetchSecretRequest) ([]byte, error) {
+	if req.Version != "" && req.Version != CurrentVersion {
+		rdr, err := s.fetchConfigVersion(ctx, bucket, root, req)
+		if err != nil {
+			return nil, err
+		}
+		defer rdr.Close()
+		return io.ReadAll(rdr)
+	}
+	fileName := configPath(root, req.Name)
 	data, err := s.store.ReadObject(ctx, bucket, fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config %s with error: %w", fileName, err)
@@ -110,16 +256,109 @@ func (s Service) FetchConfig(ctx context.Context, bucket string, req FetchSecret
 	return data, nil
 }
 
-func (s Service) getObjectPrefix(ctx context.Context, env, bucket string) (string, error) {
-	md, err := s.readMetadata(ctx, bucket, metadataFile)
+func (s Service) FetchConfigStream(ctx context.Context, bucket, root string, req FetchSecretRequest) (io.ReadCloser, error) {
+	if req.Version != "" && req.Version != CurrentVersion {
+		return s.fetchConfigVersion(ctx, bucket, root, req)
+	}
+	fileName := configPath(root, req.Name)
+	rdr, err := s.store.ReadObjectStream(ctx, bucket, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s with error: %w", fileName, err)
+	}
+	return rdr, nil
+}
+
+// DeleteConfig removes the config from the environment at root.
+func (s Service) DeleteConfig(ctx context.Context, bucket, root, name string) error {
+	fname := configPath(root, name)
+	if err := s.mustExist(ctx, bucket, fname, name); err != nil {
+		return err
+	}
+	if err := s.deleteAllVersions(ctx, bucket, root, name); err != nil {
+		return fmt.Errorf("failed to delete config %s: %w", name, err)
+	}
+	return nil
+}
+
+// RenameConfig moves the config and its history to a name that isn't used
+// yet, the copies are made before the old name is deleted.
+func (s Service) RenameConfig(ctx context.Context, bucket, root, from, to string) error {
+	src, dst := configPath(root, from), configPath(root, to)
+	if err := s.mustExist(ctx, bucket, src, from); err != nil {
+		return err
+	}
+	exists, err := s.store.ExistsObject(ctx, bucket, dst)
+	if err != nil {
+		return fmt.Errorf("failed to check config %s: %w", to, err)
+	}
+	if exists {
+		return fmt.Errorf("%s: %w", to, ErrConfigExists)
+	}
+	if err := s.copyHistory(ctx, bucket, root, from, to); err != nil {
+		return err
+	}
+	if err := s.store.CopyObject(ctx, bucket, src, dst); err != nil {
+		return fmt.Errorf("failed to copy config %s: %w", from, err)
+	}
+	if err := s.deleteAllVersions(ctx, bucket, root, from); err != nil {
+		return fmt.Errorf("failed to delete config %s: %w", from, err)
+	}
+	return nil
+}
+
+func (s Service) mustExist(ctx context.Context, bucket, fname, name string) error {
+	exists, err := s.store.ExistsObject(ctx, bucket, fname)
 	if err != nil {
-		return "", fmt.Errorf("failed to read metadata: %w", err)
+		return fmt.Errorf("failed to check config %s: %w", name, err)
 	}
-	var meta config.Metadata
-	if err := json.Unmarshal(md, &meta); err != nil {
-		return "", fmt.Errorf("failed to parse metadata file: %w", err)
+	if !exists {
+		return fmt.Errorf("%s: %w", name, ErrConfigNotFound)
+	}
+	return nil
+}
+
+// EnvironmentRoot is the path of the metadata, keys and configs of an
+// environment in the bucket.
+func EnvironmentRoot(location, env string) string {
+	return path.Join(location, env)
+}
+
+func configPath(root, name string) string {
+	return path.Join(root, secretsDir, name)
+}
+
+// ListEnvironments returns the environments with metadata under location.
+func (s Service) ListEnvironments(ctx context.Context, bucket, location string) ([]string, error) {
+	resp, err := s.ListObject(ctx, bucket, dirPrefix(location))
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+	envs := make([]string, 0)
+	for _, obj := range resp {
+		rel := strings.TrimPrefix(obj.Name, dirPrefix(location))
+		env, file, ok := strings.Cut(rel, "/")
+		if ok && file == metadataFile {
+			envs = append(envs, env)
+		}
+	}
+	return envs, nil
+}
+
+// CreateEnvironment writes the metadata of the environment of md, failing
+// when the environment already has one.
+func (s Service) CreateEnvironment(ctx context.Context, bucket string, md config.Metadata) error {
+	if err := config.ValidEnvironmentName(md.Environment); err != nil {
+		return err
+	}
+	fname := path.Join(EnvironmentRoot(md.Location, md.Environment), metadataFile)
+	exists, err := s.store.ExistsObject(ctx, bucket, fname)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%s: %w", md.Environment, ErrEnvironmentExists)
 	}
-	return meta.Location, nil
+	return s.saveObject(ctx, bucket, fname, md)
 }
 
 func (s Service) uploadPubKey(ctx context.Context, bucket string, path, key string) error {
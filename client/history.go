@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	historyDir        = "history"
+	historyTimeFormat = "20060102T150405.000Z"
+	// CurrentVersion refers to the config as it's uploaded now.
+	CurrentVersion = "current"
+)
+
+var ErrInvalidVersion = errors.New("invalid config version")
+
+// SecretVersion is a version of a config kept by the bucket versioning, or
+// copied under history by buckets without it.
+type SecretVersion struct {
+	Version   string    `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Current   bool      `json:"current"`
+}
+
+type SecretVersionsResponse struct {
+	Versions []SecretVersion `json:"versions"`
+}
+
+func historyPath(root, name string) string {
+	return path.Join(root, historyDir, name)
+}
+
+// versioned reports whether the bucket keeps versions itself, the history
+// copies are used when it can't be checked.
+func (s Service) versioned(ctx context.Context, bucket string) bool {
+	enabled, err := s.store.VersioningEnabled(ctx, bucket)
+	if err != nil {
+		log.Warn().Msgf("failed to check bucket versioning, keeping history copies: %v", err)
+		return false
+	}
+	return enabled
+}
+
+// keepHistory copies the config under history before it's overwritten.
+func (s Service) keepHistory(ctx context.Context, bucket, root, name string) error {
+	if s.versioned(ctx, bucket) {
+		return nil
+	}
+	fname := configPath(root, name)
+	exists, err := s.store.ExistsObject(ctx, bucket, fname)
+	if err != nil {
+		return fmt.Errorf("failed to check config %s: %w", name, err)
+	}
+	if !exists {
+		return nil
+	}
+	version := time.Now().UTC().Format(historyTimeFormat)
+	if err := s.store.CopyObject(ctx, bucket, fname, path.Join(historyPath(root, name), version)); err != nil {
+		return fmt.Errorf("failed to keep history of %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListConfigVersions returns the versions of the config, newest first.
+func (s Service) ListConfigVersions(ctx context.Context, bucket, root, name string) ([]SecretVersion, error) {
+	fname := configPath(root, name)
+	if s.versioned(ctx, bucket) {
+		objs, err := s.store.ListObjectVersions(ctx, bucket, fname)
+		if err != nil {
+			return nil, err
+		}
+		versions := make([]SecretVersion, len(objs))
+		for i, o := range objs {
+			versions[i] = SecretVersion{Version: o.Version, UpdatedAt: o.Updated, Current: o.Latest}
+		}
+		return versionsOf(name, versions)
+	}
+	versions := make([]SecretVersion, 0)
+	current, err := s.ListObject(ctx, bucket, fname)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+	for _, o := range current {
+		if o.Name == fname {
+			versions = append(versions, SecretVersion{Version: CurrentVersion, UpdatedAt: o.Updated, Current: true})
+		}
+	}
+	history, err := s.ListObject(ctx, bucket, historyPath(root, name)+"/")
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+	for _, o := range history {
+		versions = append(versions, SecretVersion{Version: path.Base(o.Name), UpdatedAt: o.Updated})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Current != versions[j].Current {
+			return versions[i].Current
+		}
+		return versions[i].Version > versions[j].Version
+	})
+	return versionsOf(name, versions)
+}
+
+func versionsOf(name string, versions []SecretVersion) ([]SecretVersion, error) {
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, ErrConfigNotFound)
+	}
+	return versions, nil
+}
+
+func (s Service) fetchConfigVersion(ctx context.Context, bucket, root string, req FetchSecretRequest) (io.ReadCloser, error) {
+	if strings.Contains(req.Version, "/") {
+		return nil, fmt.Errorf("%s: %w", req.Version, ErrInvalidVersion)
+	}
+	if s.versioned(ctx, bucket) {
+		return s.store.ReadObjectVersionStream(ctx, bucket, configPath(root, req.Name), req.Version)
+	}
+	rdr, err := s.store.ReadObjectStream(ctx, bucket, path.Join(historyPath(root, req.Name), req.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version %s of %s: %w", req.Version, req.Name, err)
+	}
+	return rdr, nil
+}
+
+// copyHistory copies the earlier versions of the config to another name,
+// oldest first so the bucket versions keep their order.
+func (s Service) copyHistory(ctx context.Context, bucket, root, from, to string) error {
+	if !s.versioned(ctx, bucket) {
+		history, err := s.ListObject(ctx, bucket, historyPath(root, from)+"/")
+		if err != nil {
+			return fmt.Errorf("error listing objects: %w", err)
+		}
+		for _, o := range history {
+			if err := s.store.CopyObject(ctx, bucket, o.Name, path.Join(historyPath(root, to), path.Base(o.Name))); err != nil {
+				return fmt.Errorf("failed to copy history of %s: %w", from, err)
+			}
+		}
+		return nil
+	}
+	versions, err := s.store.ListObjectVersions(ctx, bucket, configPath(root, from))
+	if err != nil {
+		return err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Latest {
+			continue
+		}
+		if err := s.copyVersion(ctx, bucket, configPath(root, from), configPath(root, to), versions[i].Version); err != nil {
+			return fmt.Errorf("failed to copy version %s of %s: %w", versions[i].Version, from, err)
+		}
+	}
+	return nil
+}
+
+func (s Service) copyVersion(ctx context.Context, bucket, src, dst, version string) error {
+	rdr, err := s.store.ReadObjectVersionStream(ctx, bucket, src, version)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+	return s.store.WriteObjectStream(ctx, bucket, dst, rdr)
+}
+
+// deleteAllVersions deletes the config with its history, the bucket keeps
+// versions of deleted objects so each one is deleted.
+func (s Service) deleteAllVersions(ctx context.Context, bucket, root, name string) error {
+	fname := configPath(root, name)
+	if !s.versioned(ctx, bucket) {
+		history, err := s.ListObject(ctx, bucket, historyPath(root, name)+"/")
+		if err != nil {
+			return fmt.Errorf("error listing objects: %w", err)
+		}
+		for _, o := range history {
+			if err := s.store.DeleteObject(ctx, bucket, o.Name); err != nil {
+				return fmt.Errorf("failed to delete history of %s: %w", name, err)
+			}
+		}
+		return s.store.DeleteObject(ctx, bucket, fname)
+	}
+	versions, err := s.store.ListObjectVersions(ctx, bucket, fname)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return !versions[i].Latest && versions[j].Latest })
+	for _, v := range versions {
+		if err := s.store.DeleteObjectVersion(ctx, bucket, fname, v.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) ListSecretVersions(req FetchSecretRequest) ([]SecretVersion, error) {
+	return c.Service.ListConfigVersions(c.ctx, c.bucket, c.root(), req.Name)
+}
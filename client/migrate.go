@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/config"
+)
+
+var (
+	ErrNoLegacyLayout   = errors.New("bucket doesn't use the legacy layout")
+	ErrAmbiguousConfigs = errors.New("several environments share the legacy layout, pick the one owning its configs")
+)
+
+// ObjectMove is an object moved to the root of its environment.
+type ObjectMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Migration moves a bucket in the legacy layout, with the metadata at the
+// bucket root and keys and configs right under its location, to the roots
+// of its environments. Keys are copied to every environment, configs to
+// the one owning them.
+type Migration struct {
+	Metadata     config.Metadata
+	Environments []string
+	Moves        []ObjectMove
+	// Legacy are the objects deleted once every environment is written.
+	Legacy []string
+}
+
+func (s Service) IsLegacyLayout(ctx context.Context, bucket string) (bool, error) {
+	return s.store.ExistsObject(ctx, bucket, metadataFile)
+}
+
+// LegacyMigration lists the objects of the legacy layout to move into the
+// roots of envs and of the environment in the legacy metadata, objects of
+// the per environment layout are left out. The configs can't tell their
+// environment, so owner picks it when there are several.
+func (s Service) LegacyMigration(ctx context.Context, bucket string, envs []string, owner string) (Migration, error) {
+	legacy, err := s.IsLegacyLayout(ctx, bucket)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to check metadata: %w", err)
+	}
+	if !legacy {
+		return Migration{}, ErrNoLegacyLayout
+	}
+	data, err := s.readMetadata(ctx, bucket, metadataFile)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	var meta config.Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return Migration{}, fmt.Errorf("failed to parse metadata file: %w", err)
+	}
+	envs = migratedEnvironments(meta.Environment, envs)
+	if owner == "" && len(envs) == 1 {
+		owner = envs[0]
+	}
+	objs, err := s.ListObject(ctx, bucket, dirPrefix(meta.Location))
+	if err != nil {
+		return Migration{}, fmt.Errorf("error listing objects: %w", err)
+	}
+	m := Migration{Metadata: meta, Environments: envs}
+	for _, obj := range objs {
+		rel := strings.TrimPrefix(obj.Name, dirPrefix(meta.Location))
+		dir, name, nested := strings.Cut(rel, "/")
+		switch {
+		case nested && dir == keysDir && !strings.Contains(name, "/"):
+			for _, env := range envs {
+				m.Moves = append(m.Moves, ObjectMove{From: obj.Name, To: path.Join(EnvironmentRoot(meta.Location, env), keysDir, name)})
+			}
+		case !nested && rel != "" && rel != metadataFile:
+			if !contains(envs, owner) {
+				return Migration{}, fmt.Errorf("%w: %s", ErrAmbiguousConfigs, strings.Join(envs, ", "))
+			}
+			m.Moves = append(m.Moves, ObjectMove{From: obj.Name, To: configPath(EnvironmentRoot(meta.Location, owner), rel)})
+		default:
+			continue
+		}
+		m.Legacy = append(m.Legacy, obj.Name)
+	}
+	return m, nil
+}
+
+// Migrate copies the objects and writes the metadata of every environment
+// before deleting anything, so a failed migration can be run again.
+func (s Service) Migrate(ctx context.Context, bucket string, m Migration) error {
+	for _, mv := range m.Moves {
+		if err := s.copyObject(ctx, bucket, mv); err != nil {
+			return err
+		}
+	}
+	for _, env := range m.Environments {
+		meta := m.Metadata
+		meta.Environment = env
+		fname := path.Join(EnvironmentRoot(meta.Location, env), metadataFile)
+		if err := s.saveObject(ctx, bucket, fname, meta); err != nil {
+			return fmt.Errorf("failed to write metadata of %s: %w", env, err)
+		}
+	}
+	for _, name := range m.Legacy {
+		if err := s.store.DeleteObject(ctx, bucket, name); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+	}
+	if err := s.store.DeleteObject(ctx, bucket, metadataFile); err != nil {
+		return fmt.Errorf("failed to delete legacy metadata: %w", err)
+	}
+	log.Info().Msgf("moved %d objects to %s", len(m.Legacy), strings.Join(m.Environments, ", "))
+	return nil
+}
+
+// migratedEnvironments are the sorted environments of the migration, with
+// the one recorded in the legacy metadata.
+func migratedEnvironments(legacy string, envs []string) []string {
+	all := make([]string, 0, len(envs)+1)
+	for _, env := range append([]string{legacy}, envs...) {
+		if env != "" && !contains(all, env) {
+			all = append(all, env)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
+
+func (s Service) copyObject(ctx context.Context, bucket string, mv ObjectMove) error {
+	if err := s.store.CopyObject(ctx, bucket, mv.From, mv.To); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", mv.From, err)
+	}
+	return nil
+}
+
+func dirPrefix(location string) string {
+	if location == "" {
+		return ""
+	}
+	return strings.TrimSuffix(location, "/") + "/"
+}
+
+// LegacyMigration migrates the environment of the client and the others
+// sharing its bucket.
+func (c *Client) LegacyMigration(envs []string, owner string) (Migration, error) {
+	return c.Service.LegacyMigration(c.ctx, c.bucket, append([]string{c.env}, envs...), owner)
+}
+
+func (c *Client) Migrate(m Migration) error {
+	c.log.Debug().Msgf("migrating %d objects to %s", len(m.Moves), strings.Join(m.Environments, ", "))
+	return c.Service.Migrate(c.ctx, c.bucket, m)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
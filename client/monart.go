@@ -47,6 +47,48 @@ func (s MonartClient) UploadSecrets(ec EncryptedConfig) error {
 	return nil
 }
 
+// UploadSecretStream encodes the config into the upload request body as it's
+// sent, instead of building the base64 payload in memory.
+func (s MonartClient) UploadSecretStream(ss SecretStream) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeEncryptedConfig(pw, ss))
+	}()
+	req, err := http.NewRequest(http.MethodPut, s.serverURL("secrets"), pr)
+	if err != nil {
+		pr.Close()
+		return fmt.Errorf("unable to build upload request: %w", err)
+	}
+	if _, err := s.call(req, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeEncryptedConfig writes the same JSON document as EncryptedConfig.
+func writeEncryptedConfig(w io.Writer, ss SecretStream) error {
+	env, err := json.Marshal(ss.Environment)
+	if err != nil {
+		return err
+	}
+	name, err := json.Marshal(ss.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"environment":%s,"name":%s,"data":"`, env, name); err != nil {
+		return err
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, ss.Data); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	_, err = io.WriteString(w, `"}`)
+	return err
+}
+
 type PublicKeyRequest struct {
 	Environment string `json:"environment"`
 }
@@ -68,6 +110,114 @@ func (s MonartClient) GetOrgPublicKeys(env string) (OrgPublicKeys, error) {
 	return result, nil
 }
 
+func (s MonartClient) GetSigners(env string) ([]Signer, error) {
+	data, err := json.Marshal(PublicKeyRequest{Environment: env})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, s.serverURL("secrets/signers"), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build signers request: %w", err)
+	}
+	var result SignersResponse
+	if _, err := s.call(req, &result); err != nil {
+		return nil, err
+	}
+	return result.Signers, nil
+}
+
+// GetKeyring isn't supported, the server manages the keys of its users.
+func (s MonartClient) GetKeyring(_ string) ([]byte, error) {
+	return nil, ErrMethodUndefined
+}
+
+func (s MonartClient) UploadKeyring(_ string, _ []byte) error {
+	return ErrMethodUndefined
+}
+
+func (s MonartClient) GetUserKeys(_, _ string) (UserKeys, error) {
+	return UserKeys{}, ErrMethodUndefined
+}
+
+type KeysRequest struct {
+	Environment string `json:"environment"`
+	UserID      string `json:"user_id,omitempty"`
+	PublicKey   string `json:"public_key,omitempty"`
+	SigningKey  string `json:"signing_key,omitempty"`
+}
+
+type KeysResponse struct {
+	Keys []UserKeys `json:"keys"`
+}
+
+func (s MonartClient) ListKeys(env string) ([]UserKeys, error) {
+	req, err := s.keysRequest(http.MethodGet, KeysRequest{Environment: env})
+	if err != nil {
+		return nil, err
+	}
+	var result KeysResponse
+	if _, err := s.call(req, &result); err != nil {
+		return nil, err
+	}
+	return result.Keys, nil
+}
+
+func (s MonartClient) AddKey(env string, keys UserKeys) error {
+	kr := KeysRequest{Environment: env, UserID: keys.UserID, PublicKey: keys.PublicKey, SigningKey: keys.SigningKey}
+	req, err := s.keysRequest(http.MethodPut, kr)
+	if err != nil {
+		return err
+	}
+	_, err = s.call(req, nil)
+	return err
+}
+
+func (s MonartClient) RemoveKey(env, userID string) error {
+	req, err := s.keysRequest(http.MethodDelete, KeysRequest{Environment: env, UserID: userID})
+	if err != nil {
+		return err
+	}
+	_, err = s.call(req, nil)
+	return err
+}
+
+func (s MonartClient) keysRequest(method string, kr KeysRequest) (*http.Request, error) {
+	data, err := json.Marshal(kr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal keys request: %w", err)
+	}
+	req, err := http.NewRequest(method, s.serverURL("secrets/keys"), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build keys request: %w", err)
+	}
+	return req, nil
+}
+
+func (s MonartClient) ListEnvironments() ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.serverURL("environments"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build environments request: %w", err)
+	}
+	var result EnvironmentsResponse
+	if _, err := s.call(req, &result); err != nil {
+		return nil, err
+	}
+	return result.Environments, nil
+}
+
+func (s MonartClient) CreateEnvironment(er EnvironmentRequest) error {
+	data, err := json.Marshal(er)
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.serverURL("environments"), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to build environment request: %w", err)
+	}
+	_, err = s.call(req, nil)
+	return err
+}
+
 func (s MonartClient) FetchSecrets(fetchReq FetchSecretRequest) ([]byte, error) {
 	data, err := json.Marshal(fetchReq)
 	if err != nil {
@@ -88,6 +238,58 @@ func (s MonartClient) FetchSecrets(fetchReq FetchSecretRequest) ([]byte, error)
 	return sec, nil
 }
 
+// FetchSecretStream returns the fetched config as a reader, the server sends
+// it within a JSON document so it's decoded in full.
+func (s MonartClient) FetchSecretStream(req FetchSecretRequest) (io.ReadCloser, error) {
+	data, err := s.FetchSecrets(req)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s MonartClient) ListSecretVersions(vr FetchSecretRequest) ([]SecretVersion, error) {
+	data, err := json.Marshal(vr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal versions request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, s.serverURL("secrets/versions"), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build config versions request: %w", err)
+	}
+	result := new(SecretVersionsResponse)
+	if _, err := s.call(req, &result); err != nil {
+		return nil, err
+	}
+	return result.Versions, nil
+}
+
+func (s MonartClient) DeleteSecret(dr DeleteSecretRequest) error {
+	data, err := json.Marshal(dr)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodDelete, s.serverURL("secrets"), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to build delete config request: %w", err)
+	}
+	_, err = s.call(req, nil)
+	return err
+}
+
+func (s MonartClient) RenameSecret(rr RenameSecretRequest) error {
+	data, err := json.Marshal(rr)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rename request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.serverURL("secrets/rename"), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to build rename config request: %w", err)
+	}
+	_, err = s.call(req, nil)
+	return err
+}
+
 func (s MonartClient) GetSecretList(cfg SecretListConfig) ([]SecretObject, error) {
 	path := fmt.Sprintf("environment/%s/secrets", cfg.Environment)
 	req, err := http.NewRequest(http.MethodGet, s.serverURL(path), nil)
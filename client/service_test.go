@@ -1,8 +1,11 @@
 package client_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/scalescape/dolores/client"
@@ -49,35 +52,231 @@ func (m *mockGCS) ExistsObject(ctx context.Context, bucketName, fileName string)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockGCS) WriteObjectStream(ctx context.Context, bucketName, fileName string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return m.Called(ctx, bucketName, fileName, data).Error(0)
+}
+
+func (m *mockGCS) ReadObjectStream(ctx context.Context, bucketName, fileName string) (io.ReadCloser, error) {
+	args := m.Called(ctx, bucketName, fileName)
+	return io.NopCloser(bytes.NewReader(args.Get(0).([]byte))), args.Error(1)
+}
+
+func (m *mockGCS) DeleteObject(ctx context.Context, bucketName, fileName string) error {
+	return m.Called(ctx, bucketName, fileName).Error(0)
+}
+
+func (m *mockGCS) CopyObject(ctx context.Context, bucketName, src, dst string) error {
+	return m.Called(ctx, bucketName, src, dst).Error(0)
+}
+
+func (m *mockGCS) VersioningEnabled(ctx context.Context, bucketName string) (bool, error) {
+	args := m.Called(ctx, bucketName)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockGCS) ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cloud.ObjectVersion, error) {
+	args := m.Called(ctx, bucketName, fileName)
+	return args.Get(0).([]cloud.ObjectVersion), args.Error(1)
+}
+
+func (m *mockGCS) ReadObjectVersionStream(ctx context.Context, bucketName, fileName, version string) (io.ReadCloser, error) {
+	args := m.Called(ctx, bucketName, fileName, version)
+	return io.NopCloser(bytes.NewReader(args.Get(0).([]byte))), args.Error(1)
+}
+
+func (m *mockGCS) DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error {
+	return m.Called(ctx, bucketName, fileName, version).Error(0)
+}
+
 func (s *serviceSuite) TestShouldWritePlainKeySuccessfully() {
 	name := "keyfile"
 	data := config.Metadata{Environment: "production"}
 	expData, err := json.Marshal(data)
-	s.gcs.On("ExistsObject", mock.AnythingOfType("*context.emptyCtx"), s.bucket, "dolores.md").Return(false, nil).Once()
-	s.gcs.On("WriteToObject", mock.AnythingOfType("*context.emptyCtx"), s.bucket, name, expData).Return(nil)
-	s.gcs.On("WriteToObject", mock.AnythingOfType("*context.emptyCtx"), s.bucket, "dolores.md", mock.AnythingOfType("[]uint8")).Return(nil).Once()
+	s.gcs.On("ExistsObject", mock.Anything, s.bucket, "production/dolores.md").Return(false, nil).Once()
+	s.gcs.On("WriteToObject", mock.Anything, s.bucket, name, expData).Return(nil)
+	s.gcs.On("WriteToObject", mock.Anything, s.bucket, "production/dolores.md", expData).Return(nil).Once()
 	require.NoError(s.T(), err)
 
-	cfg := client.Configuration{}
+	cfg := client.Configuration{Metadata: data}
 	err = s.Service.Init(s.ctx, s.bucket, cfg)
 
 	require.NoError(s.T(), err)
 }
 
 func (s *serviceSuite) TestShouldNotOverwriteMetadata() {
-	name := "dolores.md"
+	name := "secrets/qa/dolores.md"
 	cfg := client.Configuration{
 		PublicKey: "public_key",
-		Metadata:  config.Metadata{Location: "secrets"},
+		Metadata:  config.Metadata{Location: "secrets", Environment: "qa"},
 		UserID:    "test_user",
 	}
-	s.gcs.On("ExistsObject", mock.AnythingOfType("context.backgroundCtx"), s.bucket, name).Return(true, nil).Once()
-	s.gcs.On("WriteToObject", mock.AnythingOfType("context.backgroundCtx"), s.bucket, "secrets/keys/test_user.key", []byte(cfg.PublicKey)).Return(nil).Once()
+	s.gcs.On("ExistsObject", mock.Anything, s.bucket, name).Return(true, nil).Once()
+	s.gcs.On("WriteToObject", mock.Anything, s.bucket, "secrets/qa/keys/test_user.key", []byte(cfg.PublicKey)).Return(nil).Once()
 
 	err := s.Service.Init(s.ctx, s.bucket, cfg)
 
 	require.NoError(s.T(), err)
-	s.gcs.AssertNotCalled(s.T(), "WriteToObject", "dolores.md", mock.Anything)
+	s.gcs.AssertNotCalled(s.T(), "WriteToObject", mock.Anything, s.bucket, name, mock.Anything)
+}
+
+func (s *serviceSuite) TestShouldListEnvironmentsWithMetadata() {
+	objs := []cloud.Object{
+		{Name: "secrets/qa/dolores.md"}, {Name: "secrets/qa/secrets/backend"},
+		{Name: "secrets/staging/dolores.md"}, {Name: "secrets/staging/keys/alice.key"},
+	}
+	s.gcs.On("ListObject", mock.Anything, s.bucket, "secrets/").Return(objs, nil).Once()
+
+	envs, err := s.Service.ListEnvironments(s.ctx, s.bucket, "secrets")
+
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"qa", "staging"}, envs)
+}
+
+func (s *serviceSuite) TestShouldMigrateLegacyLayoutToEnvironmentRoots() {
+	ctxType := mock.Anything
+	md, err := json.Marshal(config.Metadata{Location: "secrets", Environment: "production"})
+	require.NoError(s.T(), err)
+	prodMd, err := json.Marshal(config.Metadata{Location: "secrets", Environment: "production"})
+	require.NoError(s.T(), err)
+	stagingMd, err := json.Marshal(config.Metadata{Location: "secrets", Environment: "staging"})
+	require.NoError(s.T(), err)
+	objs := []cloud.Object{{Name: "secrets/backend"}, {Name: "secrets/keys/alice.key"}, {Name: "secrets/prod/dolores.md"}}
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "dolores.md").Return(true, nil).Once()
+	s.gcs.On("ReadObject", ctxType, s.bucket, "dolores.md").Return(md, nil).Once()
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/").Return(objs, nil).Once()
+	s.gcs.On("CopyObject", ctxType, s.bucket, "secrets/backend", "secrets/staging/secrets/backend").Return(nil).Once()
+	s.gcs.On("CopyObject", ctxType, s.bucket, "secrets/keys/alice.key", "secrets/production/keys/alice.key").Return(nil).Once()
+	s.gcs.On("CopyObject", ctxType, s.bucket, "secrets/keys/alice.key", "secrets/staging/keys/alice.key").Return(nil).Once()
+	s.gcs.On("WriteToObject", ctxType, s.bucket, "secrets/production/dolores.md", prodMd).Return(nil).Once()
+	s.gcs.On("WriteToObject", ctxType, s.bucket, "secrets/staging/dolores.md", stagingMd).Return(nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, "secrets/backend").Return(nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, "secrets/keys/alice.key").Return(nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, "dolores.md").Return(nil).Once()
+
+	m, err := s.Service.LegacyMigration(s.ctx, s.bucket, []string{"staging"}, "staging")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"production", "staging"}, m.Environments)
+	require.Len(s.T(), m.Moves, 3)
+
+	require.NoError(s.T(), s.Service.Migrate(s.ctx, s.bucket, m))
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldRefuseMigratingConfigsOfUnknownEnvironment() {
+	ctxType := mock.Anything
+	md, err := json.Marshal(config.Metadata{Location: "secrets", Environment: "production"})
+	require.NoError(s.T(), err)
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "dolores.md").Return(true, nil).Once()
+	s.gcs.On("ReadObject", ctxType, s.bucket, "dolores.md").Return(md, nil).Once()
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/").Return([]cloud.Object{{Name: "secrets/backend"}}, nil).Once()
+
+	_, err = s.Service.LegacyMigration(s.ctx, s.bucket, []string{"staging"}, "")
+
+	require.ErrorIs(s.T(), err, client.ErrAmbiguousConfigs)
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldStreamConfigUnderLocation() {
+	root := client.EnvironmentRoot("secrets", "production")
+	s.gcs.On("VersioningEnabled", mock.Anything, s.bucket).Return(true, nil).Once()
+	s.gcs.On("WriteObjectStream", mock.Anything, s.bucket, "secrets/production/secrets/backend", []byte("encrypted")).Return(nil).Once()
+	s.gcs.On("ReadObjectStream", mock.Anything, s.bucket, "secrets/production/secrets/backend").Return([]byte("encrypted"), nil).Once()
+
+	err := s.Service.UploadStream(s.ctx, client.SecretStream{Environment: "production", Name: "backend", Data: strings.NewReader("encrypted")}, s.bucket, root)
+	require.NoError(s.T(), err)
+	rdr, err := s.Service.FetchConfigStream(s.ctx, s.bucket, root, client.FetchSecretRequest{Environment: "production", Name: "backend"})
+	require.NoError(s.T(), err)
+	data, err := io.ReadAll(rdr)
+
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "encrypted", string(data))
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldListAndRemoveUserKeys() {
+	ctxType := mock.Anything
+	objs := []cloud.Object{{Name: "secrets/keys/alice.key"}, {Name: "secrets/keys/alice.pub"}, {Name: "secrets/keys/keyring.json"}}
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/keys").Return(objs, nil).Once()
+	s.gcs.On("ReadObject", ctxType, s.bucket, "secrets/keys/alice.key").Return([]byte("age1alice\n"), nil).Once()
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/keys/alice.key").Return(true, nil).Once()
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/keys/alice.pub").Return(false, nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, "secrets/keys/alice.key").Return(nil).Once()
+
+	keys, err := s.Service.ListUserKeys(s.ctx, s.bucket, "secrets/keys")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []client.UserKeys{{UserID: "alice", PublicKey: "age1alice"}}, keys)
+
+	require.NoError(s.T(), s.Service.DeleteUserKeys(s.ctx, s.bucket, "secrets/keys", "alice"))
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldRenameConfigToUnusedName() {
+	ctxType := mock.Anything
+	root := client.EnvironmentRoot("secrets", "staging")
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/staging/secrets/api").Return(true, nil).Twice()
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/staging/secrets/worker").Return(true, nil).Once()
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/staging/secrets/api-v2").Return(false, nil).Once()
+	s.gcs.On("VersioningEnabled", ctxType, s.bucket).Return(false, nil).Twice()
+	history := []cloud.Object{{Name: "secrets/staging/history/api/20240101T000000.000Z"}}
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/staging/history/api/").Return(history, nil).Twice()
+	s.gcs.On("CopyObject", ctxType, s.bucket, history[0].Name, "secrets/staging/history/api-v2/20240101T000000.000Z").Return(nil).Once()
+	s.gcs.On("CopyObject", ctxType, s.bucket, "secrets/staging/secrets/api", "secrets/staging/secrets/api-v2").Return(nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, history[0].Name).Return(nil).Once()
+	s.gcs.On("DeleteObject", ctxType, s.bucket, "secrets/staging/secrets/api").Return(nil).Once()
+
+	err := s.Service.RenameConfig(s.ctx, s.bucket, root, "api", "worker")
+	require.ErrorIs(s.T(), err, client.ErrConfigExists)
+
+	require.NoError(s.T(), s.Service.RenameConfig(s.ctx, s.bucket, root, "api", "api-v2"))
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldKeepHistoryWhenVersioningIsDisabled() {
+	ctxType := mock.Anything
+	root := client.EnvironmentRoot("secrets", "qa")
+	historyFile := mock.MatchedBy(func(name string) bool { return strings.HasPrefix(name, "secrets/qa/history/api/") })
+	s.gcs.On("VersioningEnabled", ctxType, s.bucket).Return(false, nil).Times(3)
+	s.gcs.On("ExistsObject", ctxType, s.bucket, "secrets/qa/secrets/api").Return(true, nil).Once()
+	s.gcs.On("CopyObject", ctxType, s.bucket, "secrets/qa/secrets/api", historyFile).Return(nil).Once()
+	s.gcs.On("WriteObjectStream", ctxType, s.bucket, "secrets/qa/secrets/api", []byte("v2")).Return(nil).Once()
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/qa/secrets/api").Return([]cloud.Object{{Name: "secrets/qa/secrets/api"}, {Name: "secrets/qa/secrets/api-v2"}}, nil).Once()
+	s.gcs.On("ListObject", ctxType, s.bucket, "secrets/qa/history/api/").Return([]cloud.Object{
+		{Name: "secrets/qa/history/api/20240101T000000.000Z"}, {Name: "secrets/qa/history/api/20240301T000000.000Z"},
+	}, nil).Once()
+	s.gcs.On("ReadObjectStream", ctxType, s.bucket, "secrets/qa/history/api/20240101T000000.000Z").Return([]byte("v1"), nil).Once()
+
+	err := s.Service.UploadStream(s.ctx, client.SecretStream{Name: "api", Data: strings.NewReader("v2")}, s.bucket, root)
+	require.NoError(s.T(), err)
+
+	versions, err := s.Service.ListConfigVersions(s.ctx, s.bucket, root, "api")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{client.CurrentVersion, "20240301T000000.000Z", "20240101T000000.000Z"},
+		[]string{versions[0].Version, versions[1].Version, versions[2].Version})
+	require.True(s.T(), versions[0].Current)
+
+	data, err := s.Service.FetchConfig(s.ctx, s.bucket, root, client.FetchSecretRequest{Name: "api", Version: "20240101T000000.000Z"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []byte("v1"), data)
+	s.gcs.AssertExpectations(s.T())
+}
+
+func (s *serviceSuite) TestShouldDeleteEveryVersionOfConfigInVersionedBucket() {
+	ctxType := mock.Anything
+	root := client.EnvironmentRoot("secrets", "uat")
+	fname := "secrets/uat/secrets/api"
+	versions := []cloud.ObjectVersion{{Name: fname, Version: "2", Latest: true}, {Name: fname, Version: "1"}}
+	s.gcs.On("ExistsObject", ctxType, s.bucket, fname).Return(true, nil).Once()
+	s.gcs.On("VersioningEnabled", ctxType, s.bucket).Return(true, nil).Once()
+	s.gcs.On("ListObjectVersions", ctxType, s.bucket, fname).Return(versions, nil).Once()
+	older := s.gcs.On("DeleteObjectVersion", ctxType, s.bucket, fname, "1").Return(nil).Once()
+	s.gcs.On("DeleteObjectVersion", ctxType, s.bucket, fname, "2").Return(nil).Once().NotBefore(older)
+
+	require.NoError(s.T(), s.Service.DeleteConfig(s.ctx, s.bucket, root, "api"))
+	s.gcs.AssertExpectations(s.T())
 }
 
 func TestGcsService(t *testing.T) {
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/config"
 	"github.com/scalescape/dolores/secrets"
 	"github.com/urfave/cli/v2"
 )
@@ -14,6 +22,12 @@ const (
 	Name = "name"
 )
 
+var (
+	ErrInvalidDiff       = errors.New("pass one or two --version, or two --against-env to compare")
+	ErrInvalidAssignment = errors.New("pass variables as KEY=VALUE, or a single KEY with --from-stdin")
+	ErrNoKeys            = errors.New("pass the keys to change")
+)
+
 type ConfigCommand struct {
 	*cli.Command
 	rcli func(context.Context) secretsClient
@@ -36,6 +50,16 @@ func NewConfig(client GetClient) *ConfigCommand {
 	cfg.Subcommands = append(cfg.Subcommands, DecryptCommand(cfg.decryptAction))
 	cfg.Subcommands = append(cfg.Subcommands, EditCommand(cfg.editAction))
 	cfg.Subcommands = append(cfg.Subcommands, ListSecretCommand(cfg.listSecretAction))
+	cfg.Subcommands = append(cfg.Subcommands, InspectCommand(cfg.inspectAction))
+	cfg.Subcommands = append(cfg.Subcommands, DeleteCommand(cfg.deleteAction))
+	cfg.Subcommands = append(cfg.Subcommands, RenameCommand(cfg.renameAction))
+	cfg.Subcommands = append(cfg.Subcommands, HistoryCommand(cfg.historyAction))
+	cfg.Subcommands = append(cfg.Subcommands, RollbackCommand(cfg.rollbackAction))
+	cfg.Subcommands = append(cfg.Subcommands, DiffCommand(cfg.diffAction))
+	cfg.Subcommands = append(cfg.Subcommands, SetCommand(cfg.setAction))
+	cfg.Subcommands = append(cfg.Subcommands, UnsetCommand(cfg.unsetAction))
+	cfg.Subcommands = append(cfg.Subcommands, GetCommand(cfg.getAction))
+	cfg.Subcommands = append(cfg.Subcommands, PromoteCommand(cfg.promoteAction))
 	return cfg
 }
 
@@ -59,9 +83,16 @@ func (c *ConfigCommand) encryptAction(ctx *cli.Context) error {
 	env := ctx.String("environment")
 	file := ctx.String("file")
 	name := ctx.String(Name)
+	format, err := parseFormat(ctx)
+	if err != nil {
+		return err
+	}
 	log := c.log.With().Str("cmd", "config.encrypt").Str("environment", env).Logger()
 	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
-	req := secrets.EncryptConfig{Environment: env, FileName: file, Name: name}
+	req := secrets.EncryptConfig{
+		Environment: env, FileName: file, Name: name, Format: format,
+		Raw: ctx.Bool("raw"), ContentType: ctx.String("content-type"),
+	}
 	if err := secMan.Encrypt(req); err != nil {
 		return err
 	}
@@ -74,12 +105,34 @@ func (c *ConfigCommand) decryptAction(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if req.Render, err = parseRenderConfig(ctx); err != nil {
+		return err
+	}
+	req.Raw = ctx.Bool("raw")
 	log := c.log.With().Str("cmd", "config.dencrypt").Str("environment", req.Environment).Logger()
 	log.Trace().Str("cmd", "config.decrypt").Msgf("running decryption")
+	if path := ctx.String("out-file"); path != "" {
+		f, err := createOutFile(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		req.Out = f
+	}
 	sec := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
 	return sec.Decrypt(req)
 }
 
+// createOutFile opens the file decrypted content is written to, readable
+// only by the owner.
+func createOutFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file %s: %w", path, err)
+	}
+	return f, nil
+}
+
 func (c *ConfigCommand) listSecretAction(ctx *cli.Context) error {
 	env := ctx.String("environment")
 	log := c.log.With().Str("cmd", "config.list").Str("environment", env).Logger()
@@ -91,6 +144,257 @@ func (c *ConfigCommand) listSecretAction(ctx *cli.Context) error {
 	return nil
 }
 
+func (c *ConfigCommand) inspectAction(ctx *cli.Context) error {
+	env := ctx.String("environment")
+	log := c.log.With().Str("cmd", "config.inspect").Str("environment", env).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	req := secrets.InspectConfig{Environment: env, Name: ctx.String(Name), Out: os.Stdout}
+	return secMan.Inspect(req)
+}
+
+func (c *ConfigCommand) deleteAction(ctx *cli.Context) error {
+	env, name := ctx.String("environment"), ctx.String(Name)
+	log := c.log.With().Str("cmd", "config.delete").Str("environment", env).Logger()
+	if !ctx.Bool("force") {
+		confirmed := false
+		prompt := &survey.Confirm{Message: fmt.Sprintf("Delete config %s from %s?", name, env)}
+		if err := survey.AskOne(prompt, &confirmed); err != nil {
+			return fmt.Errorf("failed to confirm, pass --force to skip it: %w", err)
+		}
+		if !confirmed {
+			log.Info().Msgf("config %s is kept", name)
+			return nil
+		}
+	}
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	return secMan.DeleteSecret(secrets.DeleteSecretConfig{Environment: env, Name: name})
+}
+
+func (c *ConfigCommand) renameAction(ctx *cli.Context) error {
+	env := ctx.String("environment")
+	log := c.log.With().Str("cmd", "config.rename").Str("environment", env).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	return secMan.RenameSecret(secrets.RenameSecretConfig{Environment: env, From: ctx.String("from"), To: ctx.String("to")})
+}
+
+func (c *ConfigCommand) historyAction(ctx *cli.Context) error {
+	env := ctx.String("environment")
+	log := c.log.With().Str("cmd", "config.history").Str("environment", env).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	return secMan.History(secrets.HistoryConfig{Environment: env, Name: ctx.String(Name), Out: os.Stdout})
+}
+
+func (c *ConfigCommand) rollbackAction(ctx *cli.Context) error {
+	dcfg, err := parseDecryptConfig(ctx)
+	if err != nil {
+		return err
+	}
+	log := c.log.With().Str("cmd", "config.rollback").Str("environment", dcfg.Environment).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	return secMan.Rollback(secrets.RollbackConfig{DecryptConfig: dcfg, Version: ctx.String("version")})
+}
+
+// diffAction compares two versions in the current environment, or the
+// current config of two environments.
+func (c *ConfigCommand) diffAction(ctx *cli.Context) error {
+	versions, envs := ctx.StringSlice("version"), ctx.StringSlice("against-env")
+	fromEnv, toEnv := ctx.String("environment"), ctx.String("environment")
+	switch {
+	case len(envs) == 0 && (len(versions) == 1 || len(versions) == 2):
+	case len(versions) == 0 && len(envs) == 2:
+		fromEnv, toEnv = envs[0], envs[1]
+	default:
+		return ErrInvalidDiff
+	}
+	from, err := parseDecryptConfigFor(ctx, fromEnv)
+	if err != nil {
+		return err
+	}
+	to, err := parseDecryptConfigFor(ctx, toEnv)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		from.Version = versions[0]
+	}
+	if len(versions) > 1 {
+		to.Version = versions[1]
+	}
+	log := c.log.With().Str("cmd", "config.diff").Str("environment", fromEnv).Logger()
+	fromMan := secrets.NewSecretsManager(log, c.rcli(context.WithValue(ctx.Context, EnvValue, fromEnv)))
+	toMan := secrets.NewSecretsManager(log, c.rcli(context.WithValue(ctx.Context, EnvValue, toEnv)))
+	cfg := secrets.DiffConfig{From: from, To: to, ShowValues: ctx.Bool("show-values"), JSON: ctx.Bool("json"), Out: os.Stdout}
+	diff, err := fromMan.Diff(toMan, cfg)
+	if err != nil {
+		return err
+	}
+	return fromMan.WriteDiff(cfg, diff)
+}
+
+func parseDecryptConfigFor(ctx *cli.Context, env string) (secrets.DecryptConfig, error) {
+	if err := config.ValidEnvironmentName(env); err != nil {
+		return secrets.DecryptConfig{}, fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
+	}
+	cfg := secrets.DecryptConfig{Environment: env, Name: ctx.String(Name), Out: os.Stdout}
+	if err := parseKeyConfig(ctx, &cfg); err != nil {
+		return secrets.DecryptConfig{}, fmt.Errorf("unable to load key-file from config: %w", err)
+	}
+	if err := cfg.Valid(); err != nil {
+		return secrets.DecryptConfig{}, fmt.Errorf("pass appropriate key or key-file to decrypt %s: %w", env, err)
+	}
+	return cfg, nil
+}
+
+func (c *ConfigCommand) setAction(ctx *cli.Context) error {
+	dcfg, err := parseDecryptConfig(ctx)
+	if err != nil {
+		return err
+	}
+	values, err := parseAssignments(ctx)
+	if err != nil {
+		return err
+	}
+	log := c.log.With().Str("cmd", "config.set").Str("environment", dcfg.Environment).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	if err := secMan.SetVariables(secrets.SetConfig{DecryptConfig: dcfg, Values: values}); err != nil {
+		return err
+	}
+	log.Info().Msgf("updated %d keys of %s", len(values), dcfg.Name)
+	return nil
+}
+
+// parseAssignments reads KEY=VALUE arguments, or the value of a single key
+// from stdin without its trailing newline.
+func parseAssignments(ctx *cli.Context) ([]dolores.Variable, error) {
+	args := ctx.Args().Slice()
+	if ctx.Bool("from-stdin") {
+		if len(args) != 1 || strings.Contains(args[0], "=") {
+			return nil, ErrInvalidAssignment
+		}
+		value, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value from stdin: %w", err)
+		}
+		value = bytes.TrimSuffix(bytes.TrimSuffix(value, []byte("\n")), []byte("\r"))
+		return []dolores.Variable{{Key: []byte(args[0]), Value: value}}, nil
+	}
+	if len(args) == 0 {
+		return nil, ErrNoKeys
+	}
+	vars := make([]dolores.Variable, len(args))
+	for i, arg := range args {
+		v, err := parseAssignment(arg)
+		if err != nil {
+			return nil, err
+		}
+		vars[i] = v
+	}
+	return vars, nil
+}
+
+func parseAssignment(arg string) (dolores.Variable, error) {
+	key, value, ok := strings.Cut(arg, "=")
+	if !ok || key == "" {
+		return dolores.Variable{}, fmt.Errorf("%s: %w", key, ErrInvalidAssignment)
+	}
+	return dolores.Variable{Key: []byte(key), Value: []byte(value)}, nil
+}
+
+// assignments collects repeated KEY=VALUE flags, unlike a string slice flag
+// it doesn't split the values on commas.
+type assignments []dolores.Variable
+
+func (a *assignments) Set(arg string) error {
+	v, err := parseAssignment(arg)
+	if err != nil {
+		return err
+	}
+	*a = append(*a, v)
+	return nil
+}
+
+func (a *assignments) String() string {
+	keys := make([]string, len(*a))
+	for i, v := range *a {
+		keys[i] = string(v.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func (c *ConfigCommand) unsetAction(ctx *cli.Context) error {
+	dcfg, err := parseDecryptConfig(ctx)
+	if err != nil {
+		return err
+	}
+	keys := ctx.Args().Slice()
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+	log := c.log.With().Str("cmd", "config.unset").Str("environment", dcfg.Environment).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	if err := secMan.SetVariables(secrets.SetConfig{DecryptConfig: dcfg, Unset: keys}); err != nil {
+		return err
+	}
+	log.Info().Msgf("removed %d keys of %s", len(keys), dcfg.Name)
+	return nil
+}
+
+func (c *ConfigCommand) getAction(ctx *cli.Context) error {
+	dcfg, err := parseDecryptConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.NArg() != 1 {
+		return ErrNoKeys
+	}
+	log := c.log.With().Str("cmd", "config.get").Str("environment", dcfg.Environment).Logger()
+	secMan := secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+	return secMan.GetVariable(secrets.GetConfig{DecryptConfig: dcfg, Key: ctx.Args().First()})
+}
+
+// revive:disable function-length
+func (c *ConfigCommand) promoteAction(ctx *cli.Context) error {
+	fromEnv, toEnv := ctx.String("from"), ctx.String("to")
+	from, err := parseDecryptConfigFor(ctx, fromEnv)
+	if err != nil {
+		return err
+	}
+	to, err := parseDecryptConfigFor(ctx, toEnv)
+	if err != nil {
+		return err
+	}
+	cfg := secrets.PromoteConfig{From: from, To: to, Keys: ctx.StringSlice("keys"), ShowValues: ctx.Bool("show-values")}
+	if overrides, ok := ctx.Generic("override").(*assignments); ok && overrides != nil {
+		cfg.Overrides = *overrides
+	}
+	log := c.log.With().Str("cmd", "config.promote").Str("environment", toEnv).Logger()
+	source := secrets.NewSecretsManager(log, c.rcli(context.WithValue(ctx.Context, EnvValue, fromEnv)))
+	target := secrets.NewSecretsManager(log, c.rcli(context.WithValue(ctx.Context, EnvValue, toEnv)))
+	p, err := source.PlanPromotion(target, cfg)
+	if err != nil {
+		return err
+	}
+	if p.Preview.Empty() {
+		log.Info().Msgf("%s is already up to date in %s", to.Name, toEnv)
+		return nil
+	}
+	if err := source.WriteDiff(secrets.DiffConfig{Out: os.Stdout}, p.Preview); err != nil {
+		return err
+	}
+	if !ctx.Bool("yes") {
+		confirmed := false
+		prompt := &survey.Confirm{Message: fmt.Sprintf("Upload %s to %s?", to.Name, toEnv)}
+		if err := survey.AskOne(prompt, &confirmed); err != nil {
+			return fmt.Errorf("failed to confirm, pass --yes to skip it: %w", err)
+		}
+		if !confirmed {
+			log.Info().Msgf("%s isn't promoted", to.Name)
+			return nil
+		}
+	}
+	return target.Promote(p)
+}
+
 func EncryptCommand(action cli.ActionFunc) *cli.Command {
 	return &cli.Command{
 		Name:  "encrypt",
@@ -105,6 +409,18 @@ func EncryptCommand(action cli.ActionFunc) *cli.Command {
 				Name:     Name,
 				Required: true,
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "input format [dotenv|json|yaml|toml|properties], detected from the file extension by default",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "store the file as is, for keys, certificates or credentials that aren't env files",
+			},
+			&cli.StringFlag{
+				Name:  "content-type",
+				Usage: "content type of a raw file, detected from the file by default",
+			},
 		},
 		Action: action,
 	}
@@ -126,6 +442,35 @@ func DecryptCommand(action cli.ActionFunc) *cli.Command {
 			&cli.StringFlag{
 				Name: "key-file",
 			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "output format [dotenv|json|yaml|export|docker|kubernetes], prints the uploaded file by default",
+			},
+			&cli.StringFlag{
+				Name:  "k8s-name",
+				Usage: "name of the kubernetes secret, defaults to the config name",
+			},
+			&cli.StringFlag{
+				Name:  "k8s-namespace",
+				Usage: "namespace of the kubernetes secret",
+			},
+			&cli.StringSliceFlag{
+				Name:  "k8s-label",
+				Usage: "label (key=value) to add to the kubernetes secret",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "write the decrypted file as is",
+			},
+			&cli.StringFlag{
+				Name:    "out-file",
+				Aliases: []string{"o"},
+				Usage:   "write to the file instead of stdout",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "read configs that aren't signed by a known team member, with a warning",
+			},
 		},
 		Action: action,
 	}
@@ -147,6 +492,10 @@ func EditCommand(action cli.ActionFunc) *cli.Command {
 			&cli.StringFlag{
 				Name: "key-file",
 			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "read configs that aren't signed by a known team member, with a warning",
+			},
 		},
 		Action: action,
 	}
@@ -159,3 +508,246 @@ func ListSecretCommand(action cli.ActionFunc) *cli.Command {
 		Action: action,
 	}
 }
+
+func InspectCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "inspect",
+		Usage: "shows who uploaded the config, when and for which keys, without decrypting it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+		},
+		Action: action,
+	}
+}
+
+func DeleteCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "delete",
+		Usage: "delete the remote configuration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "delete without asking for confirmation",
+			},
+		},
+		Action: action,
+	}
+}
+
+func RenameCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "rename",
+		Usage: "rename the remote configuration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "from",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "to",
+				Required: true,
+			},
+		},
+		Action: action,
+	}
+}
+
+func HistoryCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "history",
+		Usage: "list the previous versions of the remote configuration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+		},
+		Action: action,
+	}
+}
+
+func RollbackCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "rollback",
+		Usage: "restore a previous version of the remote configuration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "version",
+				Usage:    "version to restore, as listed by config history",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "key",
+				Aliases: []string{"k"},
+			},
+			&cli.StringFlag{
+				Name: "key-file",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "restore a version that isn't signed by a known team member, with a warning",
+			},
+		},
+		Action: action,
+	}
+}
+
+func DiffCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "compare the keys of two versions, or of the config in two environments",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "version",
+				Usage: "versions to compare, as listed by config history, a single one is compared with the current config",
+			},
+			&cli.StringSliceFlag{
+				Name:  "against-env",
+				Usage: "environments to compare, pass it twice",
+			},
+			&cli.BoolFlag{
+				Name:  "show-values",
+				Usage: "print the values of the keys that differ instead of masking them",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the diff as JSON",
+			},
+			&cli.StringFlag{
+				Name:    "key",
+				Aliases: []string{"k"},
+				Usage:   "key used for both sides, the key file of each environment is used by default",
+			},
+			&cli.StringFlag{
+				Name: "key-file",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "read configs that aren't signed by a known team member, with a warning",
+			},
+		},
+		Action: action,
+	}
+}
+
+func SetCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:      "set",
+		Usage:     "set keys of the remote configuration without an editor",
+		ArgsUsage: "KEY=VALUE [KEY=VALUE...] | --from-stdin KEY",
+		Flags: append(keyFlags(),
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "from-stdin",
+				Usage: "read the value of the key from stdin, so it isn't kept in the shell history",
+			},
+		),
+		Action: action,
+	}
+}
+
+func UnsetCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:      "unset",
+		Usage:     "remove keys from the remote configuration",
+		ArgsUsage: "KEY [KEY...]",
+		Flags: append(keyFlags(),
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+		),
+		Action: action,
+	}
+}
+
+func GetCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:      "get",
+		Usage:     "print the value of a key of the remote configuration",
+		ArgsUsage: "KEY",
+		Flags: append(keyFlags(),
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+		),
+		Action: action,
+	}
+}
+
+// keyFlags select the key used to decrypt the config.
+func keyFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "key",
+			Aliases: []string{"k"},
+		},
+		&cli.StringFlag{
+			Name: "key-file",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-unsigned",
+			Usage: "read configs that aren't signed by a known team member, with a warning",
+		},
+	}
+}
+
+func PromoteCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "promote",
+		Usage: "copy a config to another environment, re-encrypted in memory for its keys",
+		Flags: append(keyFlags(),
+			&cli.StringFlag{
+				Name:     Name,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "from",
+				Usage:    "environment the config is promoted from",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "environment the config is promoted to",
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "keys",
+				Usage: "promote only the keys, merged into the target config",
+			},
+			&cli.GenericFlag{
+				Name:  "override",
+				Usage: "set KEY=VALUE in the promoted config, can be repeated",
+				Value: &assignments{},
+			},
+			&cli.BoolFlag{
+				Name:  "show-values",
+				Usage: "print the values in the preview instead of masking them",
+			},
+			&cli.BoolFlag{
+				Name:  "yes",
+				Usage: "upload without asking for confirmation",
+			},
+		),
+		Action: action,
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/config"
+	"github.com/scalescape/dolores/secrets"
+	"github.com/urfave/cli/v2"
+)
+
+type EnvCommand struct {
+	*cli.Command
+	rcli func(context.Context) secretsClient
+	log  zerolog.Logger
+}
+
+func NewEnvCommand(client GetClient) *EnvCommand {
+	cmd := &cli.Command{
+		Name:  "env",
+		Usage: "manage the environments",
+	}
+	env := &EnvCommand{
+		Command: cmd,
+		log:     log.With().Str("cmd", "env").Logger(),
+		rcli:    client,
+	}
+	env.Subcommands = append(env.Subcommands, ListEnvCommand(env.listAction))
+	env.Subcommands = append(env.Subcommands, CreateEnvCommand(env.createAction))
+	return env
+}
+
+func (c *EnvCommand) listAction(ctx *cli.Context) error {
+	secMan := secrets.NewSecretsManager(c.log.With().Str("cmd", "env.list").Logger(), c.rcli(ctx.Context))
+	return secMan.ListEnvironments(os.Stdout)
+}
+
+// createAction configures the environment like the current one with a new
+// identity and uses its client to register it, so the settings are on disk
+// before.
+func (c *EnvCommand) createAction(ctx *cli.Context) error {
+	from, name := ctx.String("environment"), ctx.String("name")
+	if err := config.ValidEnvironmentName(name); err != nil {
+		return err
+	}
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	keyFile := filepath.Join(config.Dir, name+".key")
+	if err := d.CopyEnvironment(from, name, keyFile); err != nil {
+		return err
+	}
+	var passphrase []byte
+	if ctx.Bool("protect") {
+		if passphrase, err = newPassphrase(); err != nil {
+			return err
+		}
+	}
+	if _, err := generateKey(keyFile, passphrase); err != nil {
+		return err
+	}
+	log := c.log.With().Str("cmd", "env.create").Str("environment", name).Logger()
+	undo := func(err error) error {
+		delete(d.Environments, name)
+		if serr := d.SaveToDisk(); serr != nil {
+			log.Error().Msgf("failed to remove %s from dolores config: %v", name, serr)
+		}
+		if rerr := os.Remove(keyFile); rerr != nil {
+			log.Error().Msgf("failed to remove %s: %v", keyFile, rerr)
+		}
+		return err
+	}
+	if err := d.SaveToDisk(); err != nil {
+		return undo(fmt.Errorf("error saving dolores config: %w", err))
+	}
+	secMan := secrets.NewSecretsManager(log, c.rcli(context.WithValue(ctx.Context, EnvValue, name)))
+	if err := secMan.CreateEnvironment(secrets.EnvironmentConfig{Name: name, From: from}); err != nil {
+		return undo(err)
+	}
+	return nil
+}
+
+func ListEnvCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:   "list",
+		Usage:  "list the environments",
+		Action: action,
+	}
+}
+
+func CreateEnvCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:   "create",
+		Usage:  "create an environment set up like the current one, e.g. dolores --env staging env create --name qa",
+		Action: action,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "name",
+				Usage:    "name of the new environment",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "protect",
+				Usage: "encrypt the identity file of the new environment with a passphrase",
+			},
+		},
+	}
+}
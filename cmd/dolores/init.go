@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,17 +9,22 @@ import (
 	"path/filepath"
 	"time"
 
-	"filippo.io/age"
+	"filippo.io/age/armor"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/scalescape/dolores"
 	"github.com/scalescape/dolores/client"
 	"github.com/scalescape/dolores/config"
+	"github.com/scalescape/dolores/lib"
+	"github.com/scalescape/dolores/secrets"
 	"github.com/urfave/cli/v2"
 )
 
-var ErrInvalidEnvironment = errors.New("invalid environment")
+var (
+	ErrInvalidEnvironment = errors.New("invalid environment")
+	ErrPassphraseMismatch = errors.New("passphrases don't match")
+)
 
 type InitCommand struct {
 	*cli.Command
@@ -37,6 +43,16 @@ func NewInitCommand(newCli GetClient) *cli.Command {
 		Name:   "init",
 		Usage:  "bootstrap with settings",
 		Action: ic.initialize,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "key-file",
+				Usage: "register an existing age or ssh key (e.g. ~/.ssh/id_ed25519) instead of generating one",
+			},
+			&cli.BoolFlag{
+				Name:  "protect",
+				Usage: "encrypt the identity file with a passphrase",
+			},
+		},
 	}
 	return cmd
 }
@@ -167,24 +183,114 @@ func (c *InitCommand) createConfig(configDir, keyFile string) error {
 	return nil
 }
 
-func (c *InitCommand) generateKey(fname string) (string, error) {
+func generateKey(fname string, passphrase []byte) (string, error) {
+	data, pubKey, err := dolores.GenerateIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
 	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
 	if err != nil {
 		return "", fmt.Errorf("failed to create file %s with error %w", fname, err)
 	}
 	defer f.Close()
-	k, err := age.GenerateX25519Identity()
-	if err != nil {
-		return "", fmt.Errorf("failed to generate key: %w", err)
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write key file %s: %w", fname, err)
 	}
-	pubKey := k.Recipient().String()
-	fmt.Fprintf(f, "# created: %s\n", time.Now().Format(time.RFC3339))
-	fmt.Fprintf(f, "# public key: %s\n", pubKey)
-	fmt.Fprintf(f, "%s\n", k)
 	log.Info().Msgf("successfully generated asymmetric key")
 	return pubKey, nil
 }
 
+// signingKey creates the key signing uploaded configs, an existing key is
+// kept so configs signed with it still verify.
+func (c *InitCommand) signingKey(fname string, passphrase []byte) (string, error) {
+	if _, err := os.Stat(fname); err == nil {
+		key, err := dolores.LoadSigningKey(fname, lib.ReadPassphrase)
+		if err != nil {
+			return "", err
+		}
+		log.Info().Msgf("signing key already exists at %s", fname)
+		return key.Public(), nil
+	}
+	key, err := dolores.GenerateSigningKey()
+	if err != nil {
+		return "", err
+	}
+	data := key.Bytes()
+	if passphrase != nil {
+		if data, err = dolores.ProtectIdentity(data, passphrase); err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(fname, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write signing key %s: %w", fname, err)
+	}
+	log.Info().Msgf("successfully generated signing key")
+	return key.Public(), nil
+}
+
+// joinKeyring creates the keyring with the user's keys for the first user,
+// others have to be approved by a member.
+func (c *InitCommand) joinKeyring(cli secretsClient, env, userID, publicKey string) error {
+	data, err := cli.GetKeyring(env)
+	if errors.Is(err, client.ErrMethodUndefined) {
+		return nil
+	} else if errors.Is(err, client.ErrKeyringNotFound) {
+		return secrets.NewSecretsManager(c.log, cli).Approve(secrets.ApproveConfig{Environment: env, UserID: userID})
+	} else if err != nil {
+		return fmt.Errorf("failed to read keyring: %w", err)
+	}
+	if k, err := dolores.ParseKeyring(data); err == nil {
+		if e, ok := k.Entry(userID); ok && e.PublicKey == publicKey {
+			return nil
+		}
+	}
+	log.Info().Msgf("ask a team member to approve your keys: dolores --env %s keys approve --user %s", env, userID)
+	return nil
+}
+
+// protectKey encrypts an existing plain identity file in place.
+func (c *InitCommand) protectKey(fname string, passphrase []byte) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return fmt.Errorf("failed to read key file %s: %w", fname, err)
+	}
+	if bytes.HasPrefix(data, []byte(armor.Header)) {
+		log.Info().Msgf("key file %s is already protected", fname)
+		return nil
+	}
+	if bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		log.Warn().Msgf("only age identities can be protected, protect ssh keys with ssh-keygen -p")
+		return nil
+	}
+	protected, err := dolores.ProtectIdentity(data, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fname, protected, 0o600); err != nil {
+		return fmt.Errorf("failed to write key file %s: %w", fname, err)
+	}
+	log.Info().Msgf("protected key file %s with passphrase", fname)
+	return nil
+}
+
+// newPassphrase asks for a passphrase twice, or reads it from the env.
+func newPassphrase() ([]byte, error) {
+	if pass := os.Getenv(dolores.PassphraseEnv); pass != "" {
+		return []byte(pass), nil
+	}
+	var pass, confirm string
+	if err := survey.AskOne(&survey.Password{Message: "Passphrase to protect the key"}, &pass, survey.WithValidator(survey.Required)); err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if err := survey.AskOne(&survey.Password{Message: "Confirm passphrase"}, &confirm); err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if pass != confirm {
+		return nil, ErrPassphraseMismatch
+	}
+	return []byte(pass), nil
+}
+
 // revive:disable:function-length:cyclomatic:cognitive-complexity:cyclomatic
 func (c *InitCommand) initialize(cctx *cli.Context) error {
 	env := cctx.String("environment")
@@ -199,10 +305,19 @@ func (c *InitCommand) initialize(cctx *cli.Context) error {
 	if err := c.createConfig(config.Dir, keyFilePath); err != nil {
 		return err
 	}
+	if kf := cctx.String("key-file"); kf != "" {
+		keyFilePath = lib.AbsPath(kf)
+	}
+	var passphrase []byte
+	if cctx.Bool("protect") {
+		if passphrase, err = newPassphrase(); err != nil {
+			return err
+		}
+	}
 	_, err = os.Stat(keyFilePath)
 	var publicKey string
-	if os.IsNotExist(err) {
-		publicKey, err = c.generateKey(keyFilePath)
+	if os.IsNotExist(err) && cctx.String("key-file") == "" {
+		publicKey, err = generateKey(keyFilePath, passphrase)
 		if err != nil {
 			return err
 		}
@@ -212,18 +327,35 @@ func (c *InitCommand) initialize(cctx *cli.Context) error {
 			return fmt.Errorf("error reading public key: %s %w", keyFilePath, err)
 		}
 		log.Info().Msgf("asymmetric key already exists at %s", keyFilePath)
+		if passphrase != nil {
+			if err := c.protectKey(keyFilePath, passphrase); err != nil {
+				return err
+			}
+		}
+	}
+	signKeyPath := filepath.Join(config.Dir, env+".sign")
+	signingKey, err := c.signingKey(signKeyPath, passphrase)
+	if err != nil {
+		return err
+	}
+	// keep the environments configured before
+	d, _ := config.LoadFromDisk()
+	if d == nil {
+		d = new(config.Dolores)
 	}
-	d := &config.Dolores{}
 	md := inp.ToMetadata(env)
-	d.AddEnvironment(env, keyFilePath, md)
+	d.AddEnvironment(env, keyFilePath, signKeyPath, inp.UserID, md)
 	if err := d.SaveToDisk(); err != nil {
 		return fmt.Errorf("error saving dolores config: %w", err)
 	}
 	cli := c.rcli(cctx.Context)
-	cfg := client.Configuration{PublicKey: publicKey, Metadata: md, UserID: inp.UserID}
+	cfg := client.Configuration{PublicKey: publicKey, SigningKey: signingKey, Metadata: md, UserID: inp.UserID}
 	if err := cli.Init(cctx.Context, md.Bucket, cfg); err != nil {
 		return err
 	}
+	if err := c.joinKeyring(cli, env, inp.UserID, publicKey); err != nil {
+		return err
+	}
 	log.Info().Msgf("successfully initialized dolores")
 	return nil
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/secrets"
+	"github.com/urfave/cli/v2"
+)
+
+type KeysCommand struct {
+	*cli.Command
+	rcli func(context.Context) secretsClient
+	log  zerolog.Logger
+}
+
+func NewKeys(client GetClient) *KeysCommand {
+	cmd := &cli.Command{
+		Name:  "keys",
+		Usage: "manage the team keyring",
+	}
+	keys := &KeysCommand{
+		Command: cmd,
+		log:     log.With().Str("cmd", "keys").Logger(),
+		rcli:    client,
+	}
+	keys.Subcommands = append(keys.Subcommands, ListKeysCommand(keys.listAction))
+	keys.Subcommands = append(keys.Subcommands, AddKeyCommand(keys.addAction))
+	keys.Subcommands = append(keys.Subcommands, RemoveKeyCommand(keys.removeAction))
+	keys.Subcommands = append(keys.Subcommands, ShowMineCommand(keys.showMineAction))
+	keys.Subcommands = append(keys.Subcommands, ApproveCommand(keys.approveAction))
+	keys.Subcommands = append(keys.Subcommands, RotateCommand(keys.rotateAction))
+	return keys
+}
+
+func (c *KeysCommand) manager(ctx *cli.Context, name string) secrets.SecretManager {
+	env := ctx.String("environment")
+	log := c.log.With().Str("cmd", "keys."+name).Str("environment", env).Logger()
+	return secrets.NewSecretsManager(log, c.rcli(ctx.Context))
+}
+
+func (c *KeysCommand) listAction(ctx *cli.Context) error {
+	return c.manager(ctx, "list").ListKeys(secrets.KeysConfig{Environment: ctx.String("environment"), Out: os.Stdout})
+}
+
+func (c *KeysCommand) addAction(ctx *cli.Context) error {
+	cfg := secrets.KeysConfig{
+		Environment: ctx.String("environment"),
+		UserID:      ctx.String("user"),
+		PublicKey:   ctx.String("public-key"),
+	}
+	return c.manager(ctx, "add").AddKey(cfg)
+}
+
+func (c *KeysCommand) removeAction(ctx *cli.Context) error {
+	cfg := secrets.KeysConfig{Environment: ctx.String("environment"), UserID: ctx.String("user")}
+	secMan := c.manager(ctx, "remove")
+	if ctx.Bool("report") {
+		if err := c.report(ctx, secMan, cfg.UserID); err != nil {
+			return err
+		}
+	}
+	return secMan.RemoveKey(cfg)
+}
+
+// report prints what the user could read before their key is removed.
+func (c *KeysCommand) report(ctx *cli.Context, secMan secrets.SecretManager, userID string) error {
+	cfg := secrets.ReportConfig{
+		DecryptConfig: secrets.DecryptConfig{Environment: ctx.String("environment"), Out: os.Stdout},
+		UserID:        userID,
+		PreviousKeys:  ctx.StringSlice("previous-key"),
+		JSON:          ctx.Bool("json"),
+		Mark:          ctx.Bool("mark-rotation"),
+	}
+	if err := parseKeyConfig(ctx, &cfg.DecryptConfig); err != nil {
+		return err
+	}
+	report, err := secMan.ExposureReport(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build exposure report: %w", err)
+	}
+	if err := secMan.WriteReport(cfg, report); err != nil {
+		return err
+	}
+	if cfg.Mark {
+		return secMan.MarkRotation(cfg, report)
+	}
+	return nil
+}
+
+func (c *KeysCommand) showMineAction(ctx *cli.Context) error {
+	return c.manager(ctx, "show-mine").ShowMine(secrets.KeysConfig{Environment: ctx.String("environment"), Out: os.Stdout})
+}
+
+func (c *KeysCommand) approveAction(ctx *cli.Context) error {
+	cfg := secrets.ApproveConfig{Environment: ctx.String("environment"), UserID: ctx.String("user")}
+	return c.manager(ctx, "approve").Approve(cfg)
+}
+
+func (c *KeysCommand) rotateAction(ctx *cli.Context) error {
+	env := ctx.String("environment")
+	secMan := c.manager(ctx, "rotate")
+	if ctx.Bool("confirm") {
+		return secMan.ConfirmRotation(env)
+	}
+	cfg := secrets.RotateConfig{Environment: env, AllowUnsigned: ctx.Bool("allow-unsigned"), Out: os.Stdout}
+	if ctx.Bool("protect") {
+		var err error
+		if cfg.Passphrase, err = newPassphrase(); err != nil {
+			return err
+		}
+	}
+	return secMan.RotateKey(cfg)
+}
+
+func userFlag(usage string) cli.Flag {
+	return &cli.StringFlag{
+		Name:     "user",
+		Usage:    usage,
+		Required: true,
+	}
+}
+
+func ListKeysCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:   "list",
+		Usage:  "shows the team members configs are encrypted to",
+		Action: action,
+	}
+}
+
+func AddKeyCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "add",
+		Usage: "add the public key of a team member who can't run init",
+		Flags: []cli.Flag{
+			userFlag("id of the team member"),
+			&cli.StringFlag{
+				Name:     "public-key",
+				Usage:    "age or ssh public key of the team member",
+				Required: true,
+			},
+		},
+		Action: action,
+	}
+}
+
+func RemoveKeyCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "remove",
+		Usage: "stop encrypting configs to a team member",
+		Flags: []cli.Flag{
+			userFlag("id of the team member"),
+			&cli.BoolFlag{
+				Name:  "report",
+				Usage: "list the configs and keys the member could read, as a rotation checklist",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the report as json",
+			},
+			&cli.BoolFlag{
+				Name:  "mark-rotation",
+				Usage: "flag the reported keys as needing rotation in the configs",
+			},
+			&cli.StringSliceFlag{
+				Name:  "previous-key",
+				Usage: "earlier public key of the member, configs encrypted to it are reported too",
+			},
+			&cli.StringFlag{
+				Name:    "key",
+				Aliases: []string{"k"},
+			},
+			&cli.StringFlag{
+				Name: "key-file",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "report configs that aren't signed by a known team member, with a warning",
+			},
+		},
+		Action: action,
+	}
+}
+
+func ShowMineCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:   "show-mine",
+		Usage:  "shows your keys and whether they're approved",
+		Action: action,
+	}
+}
+
+func ApproveCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:   "approve",
+		Usage:  "add the keys a user published at init to the keyring, signed by you",
+		Flags:  []cli.Flag{userFlag("id the user initialized with")},
+		Action: action,
+	}
+}
+
+func RotateCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name:  "rotate",
+		Usage: "replace your identity and re-encrypt the configs to the new key",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "protect",
+				Usage: "encrypt the new identity file with a passphrase",
+			},
+			&cli.BoolFlag{
+				Name:  "confirm",
+				Usage: "delete the identity backed up by the last rotation",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "re-encrypt configs that aren't signed by a known team member, with a warning",
+			},
+		},
+		Action: action,
+	}
+}
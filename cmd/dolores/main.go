@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores"
 	"github.com/scalescape/dolores/client"
 	"github.com/scalescape/dolores/config"
 	"github.com/urfave/cli/v2"
@@ -14,10 +16,24 @@ import (
 
 type secretsClient interface {
 	UploadSecrets(req client.EncryptedConfig) error
+	UploadSecretStream(req client.SecretStream) error
 	FetchSecrets(req client.FetchSecretRequest) ([]byte, error)
+	FetchSecretStream(req client.FetchSecretRequest) (io.ReadCloser, error)
 	GetOrgPublicKeys(env string) (client.OrgPublicKeys, error)
 	Init(ctx context.Context, bucket string, cfg client.Configuration) error
 	GetSecretList(req client.SecretListConfig) ([]client.SecretObject, error)
+	GetSigners(env string) ([]client.Signer, error)
+	GetKeyring(env string) ([]byte, error)
+	UploadKeyring(env string, data []byte) error
+	GetUserKeys(env, userID string) (client.UserKeys, error)
+	ListKeys(env string) ([]client.UserKeys, error)
+	AddKey(env string, keys client.UserKeys) error
+	RemoveKey(env, userID string) error
+	ListEnvironments() ([]string, error)
+	CreateEnvironment(req client.EnvironmentRequest) error
+	DeleteSecret(req client.DeleteSecretRequest) error
+	RenameSecret(req client.RenameSecretRequest) error
+	ListSecretVersions(req client.FetchSecretRequest) ([]client.SecretVersion, error)
 }
 
 type CtxKey string
@@ -29,6 +45,7 @@ func main() {
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
 	cli.VersionPrinter = VersionDisplay
+	dolores.Version = version
 
 	app := &cli.App{
 		Name:    "Dolores",
@@ -37,12 +54,12 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name: "environment", Aliases: []string{"env"},
-				Usage:    "environment where you want to manage [staging|production]",
+				Usage:    "environment where you want to manage, see `dolores env list`",
 				Required: true,
 				Action: func(cctx *cli.Context, v string) error {
 					cctx.Context = context.WithValue(cctx.Context, EnvValue, v)
-					if v == "" {
-						return fmt.Errorf("invalid flag: %w", ErrInvalidEnvironment)
+					if err := config.ValidEnvironmentName(v); err != nil {
+						return fmt.Errorf("invalid flag: %w: %w", ErrInvalidEnvironment, err)
 					}
 					return nil
 				},
@@ -66,6 +83,10 @@ func main() {
 			NewRunner(newClient).Command,
 			NewMonitor(),
 			NewInitCommand(newClient),
+			NewKeys(newClient).Command,
+			NewRekeyCommand(newClient),
+			NewEnvCommand(newClient).Command,
+			NewMigrateCommand(),
 		},
 	}
 	if err := app.Run(os.Args); err != nil {
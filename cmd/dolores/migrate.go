@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+	"github.com/urfave/cli/v2"
+)
+
+// NewMigrateCommand moves a bucket from the legacy layout, shared by every
+// environment, to the roots of the environments configured with the
+// bucket. It works on the bucket directly, the server manages its own
+// layout.
+func NewMigrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "move the objects of the legacy bucket layout under <location>/<environment>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only show the objects to move",
+			},
+			&cli.StringFlag{
+				Name:  "configs-env",
+				Usage: "environment owning the configs when several environments share the bucket",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			env := ctx.String("environment")
+			envs, err := sharingBucket(env)
+			if err != nil {
+				return err
+			}
+			cli := newStorageClient(ctx.Context, env)
+			m, err := cli.LegacyMigration(envs, ctx.String("configs-env"))
+			if errors.Is(err, client.ErrNoLegacyLayout) {
+				log.Info().Msgf("bucket already uses the per environment layout")
+				return nil
+			} else if err != nil {
+				return err
+			}
+			lineFormat := "%-50s -> %s\n"
+			for _, mv := range m.Moves {
+				fmt.Fprintf(os.Stdout, lineFormat, mv.From, mv.To)
+			}
+			log.Info().Msgf("environments: %s", strings.Join(m.Environments, ", "))
+			if ctx.Bool("dry-run") {
+				return nil
+			}
+			return cli.Migrate(m)
+		},
+	}
+}
+
+// sharingBucket returns the other environments configured with the bucket
+// of env.
+func sharingBucket(env string) ([]string, error) {
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return nil, fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	ec, ok := d.Environments[env]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", env, config.ErrUnknownEnvironment)
+	}
+	var envs []string
+	for _, name := range d.Names() {
+		if name != env && d.Environments[name].Bucket == ec.Bucket {
+			envs = append(envs, name)
+		}
+	}
+	return envs, nil
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scalescape/dolores"
 	"github.com/scalescape/dolores/config"
+	"github.com/scalescape/dolores/lib"
 	"github.com/scalescape/dolores/secrets"
 	"github.com/urfave/cli/v2"
 )
@@ -31,6 +33,7 @@ func parseKeyConfig(ctx *cli.Context, cfg *secrets.DecryptConfig) error {
 	}
 	cfg.KeyFile = keyFile
 	cfg.Key = key
+	cfg.AllowUnsigned = ctx.Bool("allow-unsigned")
 
 	return nil
 }
@@ -53,6 +56,58 @@ func parseDecryptConfig(ctx *cli.Context) (secrets.DecryptConfig, error) {
 	return req, nil
 }
 
+func parseFormat(ctx *cli.Context) (dolores.Format, error) {
+	if !ctx.IsSet("format") {
+		return "", nil
+	}
+	format, err := dolores.ParseFormat(ctx.String("format"))
+	if err != nil {
+		return "", fmt.Errorf("invalid format flag: %w", err)
+	}
+	return format, nil
+}
+
+func parseRenderConfig(ctx *cli.Context) (dolores.RenderConfig, error) {
+	if !ctx.IsSet("output") {
+		return dolores.RenderConfig{}, nil
+	}
+	format, err := dolores.ParseOutputFormat(ctx.String("output"))
+	if err != nil {
+		return dolores.RenderConfig{}, fmt.Errorf("invalid output flag: %w", err)
+	}
+	cfg := dolores.RenderConfig{
+		Format:    format,
+		Name:      ctx.String("k8s-name"),
+		Namespace: ctx.String("k8s-namespace"),
+	}
+	if cfg.Name == "" {
+		cfg.Name = ctx.String(Name)
+	}
+	for _, label := range ctx.StringSlice("k8s-label") {
+		k, v, ok := strings.Cut(label, "=")
+		if !ok || k == "" {
+			return dolores.RenderConfig{}, fmt.Errorf("invalid label %s: %w", label, dolores.ErrInvalidConfiguraion)
+		}
+		if cfg.Labels == nil {
+			cfg.Labels = make(map[string]string)
+		}
+		cfg.Labels[k] = v
+	}
+	return cfg, nil
+}
+
+func parseMounts(values []string) (map[string]string, error) {
+	mounts := make(map[string]string, len(values))
+	for _, m := range values {
+		name, path, ok := strings.Cut(m, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid mount %s, expected name=path: %w", m, dolores.ErrInvalidConfiguraion)
+		}
+		mounts[name] = lib.AbsPath(path)
+	}
+	return mounts, nil
+}
+
 func parseServerConfig(cctx *cli.Context) (config.Server, error) {
 	cfg := config.Server{
 		Port: cctx.Int("port"), Host: cctx.String("host"),
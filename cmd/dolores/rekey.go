@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/secrets"
+	"github.com/urfave/cli/v2"
+)
+
+func NewRekeyCommand(newCli GetClient) *cli.Command {
+	return &cli.Command{
+		Name:  "rekey",
+		Usage: "re-encrypt configs to the current team keys",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  Name,
+				Usage: "config to re-encrypt, can be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "re-encrypt every config of the environment",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only show which configs are out of date",
+			},
+			&cli.StringFlag{
+				Name:    "key",
+				Aliases: []string{"k"},
+			},
+			&cli.StringFlag{
+				Name: "key-file",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-unsigned",
+				Usage: "re-encrypt configs that aren't signed by a known team member, with a warning",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			env := ctx.String("environment")
+			cfg := secrets.RekeyConfig{
+				DecryptConfig: secrets.DecryptConfig{Environment: env, Out: os.Stdout},
+				Names:         ctx.StringSlice(Name),
+				All:           ctx.Bool("all"),
+				DryRun:        ctx.Bool("dry-run"),
+			}
+			if err := parseKeyConfig(ctx, &cfg.DecryptConfig); err != nil {
+				return err
+			}
+			log := log.With().Str("cmd", "rekey").Str("environment", env).Logger()
+			return secrets.NewSecretsManager(log, newCli(ctx.Context)).Rekey(cfg)
+		},
+	}
+}
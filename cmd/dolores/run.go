@@ -1,8 +1,6 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -14,6 +12,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores"
 	"github.com/scalescape/dolores/lib"
 	"github.com/scalescape/dolores/secrets"
 	"github.com/urfave/cli/v2"
@@ -55,21 +54,47 @@ func (c *Runner) environ(ctx context.Context, name string) ([]string, error) {
 	log := log.With().Str("cmd", "run").Str("environment", c.environment).Logger()
 	log.Debug().Msgf("loading configuration %s before running", name)
 	sec := secrets.NewSecretsManager(log, c.rcli(ctx))
-	if err := sec.Decrypt(c.DecryptConfig); err != nil {
+	ef, err := sec.Load(c.DecryptConfig)
+	if err != nil {
 		return nil, err
 	}
-	scanner := bufio.NewScanner(c.configBuffer)
-	for scanner.Scan() {
-		v := scanner.Text()
-		envs = append(envs, v)
+	if ef.Format == dolores.FormatRaw {
+		log.Warn().Msgf("skipping raw config %s, use --mount to write it to a file", name)
+		return envs, nil
+	}
+	for _, v := range ef.Variables {
+		envs = append(envs, v.Env())
 	}
 	return envs, nil
 }
 
+// mount writes the raw configs to their paths before the command runs.
+func (c *Runner) mount(ctx context.Context) error {
+	sec := secrets.NewSecretsManager(log.Logger, c.rcli(ctx))
+	for name, path := range c.mounts {
+		f, err := createOutFile(path)
+		if err != nil {
+			return err
+		}
+		cfg := c.DecryptConfig
+		cfg.Name, cfg.Out, cfg.Raw = name, f, true
+		err = sec.Decrypt(cfg)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to mount %s: %w", name, err)
+		}
+		log.Debug().Msgf("mounted config %s at %s", name, path)
+	}
+	return nil
+}
+
 // revive:disable function-length
 func (c *Runner) runScript(ctx context.Context, cmdName string, args []string) error {
 	log.Trace().Msgf("executing cmd: %s with args: %s", cmdName, args)
 	cmd := exec.CommandContext(ctx, cmdName, args...)
+	if err := c.mount(ctx); err != nil {
+		return err
+	}
 	if c.configName != "" {
 		vars, err := c.environ(ctx, c.configName)
 		if err != nil {
@@ -111,11 +136,11 @@ type Runner struct {
 	*cli.Command
 	rcli GetClient
 	execCommand
-	exitStatus   int
-	wg           *sync.WaitGroup
-	configName   string
-	configBuffer *bytes.Buffer
-	environment  string
+	exitStatus  int
+	wg          *sync.WaitGroup
+	configName  string
+	mounts      map[string]string
+	environment string
 	secrets.DecryptConfig
 }
 
@@ -149,20 +174,27 @@ func (c *Runner) parse(ctx *cli.Context) error {
 	}
 	c.configName = ctx.String("with-config")
 	c.environment = ctx.String("environment")
-	if c.configName != "" && c.environment == "" {
+	mounts, err := parseMounts(ctx.StringSlice("mount"))
+	if err != nil {
+		return err
+	}
+	c.mounts = mounts
+	if (c.configName != "" || len(mounts) > 0) && c.environment == "" {
 		return fmt.Errorf("pass environment: %w", ErrInvalidEnvironment)
 	}
-	if c.configName != "" && c.environment != "" {
+	if c.configName != "" || len(mounts) > 0 {
 		c.DecryptConfig = secrets.DecryptConfig{
 			Name:        c.configName,
 			Environment: c.environment,
-			Out:         c.configBuffer,
+			Out:         io.Discard,
 		}
 		if err := parseKeyConfig(ctx, &c.DecryptConfig); err != nil {
 			return err
 		}
-		if err := c.DecryptConfig.Valid(); err != nil {
-			return err
+		if c.configName != "" {
+			if err := c.DecryptConfig.Valid(); err != nil {
+				return err
+			}
 		}
 	}
 	if err := req.Valid(); err != nil {
@@ -188,9 +220,8 @@ func (c *Runner) runAction(ctx *cli.Context) error {
 
 func NewRunner(client GetClient) Runner {
 	cmd := Runner{
-		rcli:         client,
-		wg:           new(sync.WaitGroup),
-		configBuffer: bytes.NewBuffer(make([]byte, 0)),
+		rcli: client,
+		wg:   new(sync.WaitGroup),
 		Command: &cli.Command{
 			Name:  "run",
 			Usage: "execute a script or command with secrets loaded",
@@ -208,6 +239,14 @@ func NewRunner(client GetClient) Runner {
 				&cli.StringFlag{
 					Name: "key",
 				},
+				&cli.StringSliceFlag{
+					Name:  "mount",
+					Usage: "write a raw config to a file before running, as name=path",
+				},
+				&cli.BoolFlag{
+					Name:  "allow-unsigned",
+					Usage: "load configs that aren't signed by a known team member, with a warning",
+				},
 			},
 		},
 	}
@@ -33,6 +33,12 @@ var (
 	File     = filepath.Join(Dir, "dolores.json")
 )
 
+// KeyringPin is the local copy of the environment's keyring, trusted on
+// first use.
+func KeyringPin(env string) string {
+	return filepath.Join(Dir, env+".keyring")
+}
+
 type Cloud struct {
 	ApplicationCredentials string `split_words:"true"`
 	StorageBucket          string `split_words:"true"`
@@ -50,7 +56,8 @@ type Metadata struct {
 
 type Client struct {
 	Cloud
-	Provider string
+	Provider    string
+	Environment string
 }
 
 func (c Client) BucketName() string {
@@ -72,7 +79,7 @@ func (c Client) Valid() error {
 
 // revive:disable function-length
 func LoadClient(ctx context.Context, env string) (Client, error) {
-	var cfg Client
+	cfg := Client{Environment: env}
 	d, err := LoadFromDisk()
 	if err != nil {
 		return Client{}, fmt.Errorf("dolores not initialized yet: %w", err)
@@ -3,31 +3,102 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
-var ErrInvalidDoloresConfig = errors.New("invalid dolores config")
+var (
+	ErrInvalidDoloresConfig   = errors.New("invalid dolores config")
+	ErrInvalidEnvironmentName = errors.New("environment names are lowercase letters, digits, '-' and '_'")
+	ErrUnknownEnvironment     = errors.New("environment isn't configured, see `dolores env list`")
+)
+
+var environmentName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidEnvironmentName checks the name can be used in paths and urls.
+func ValidEnvironmentName(name string) error {
+	if !environmentName.MatchString(name) {
+		return fmt.Errorf("%q: %w", name, ErrInvalidEnvironmentName)
+	}
+	return nil
+}
+
+// ValidEnvironment checks the environment is configured on disk. Without
+// a config on disk the server has the environments, so it checks them.
+func ValidEnvironment(name string) error {
+	if err := ValidEnvironmentName(name); err != nil {
+		return err
+	}
+	d, err := LoadFromDisk()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	if _, ok := d.Environments[name]; !ok {
+		return fmt.Errorf("%s: %w", name, ErrUnknownEnvironment)
+	}
+	return nil
+}
 
 type Environment struct {
 	Metadata `json:"metadata"`
 	KeyFile  string `json:"key_file"`
+	UserID   string `json:"user_id"`
+	// SigningKeyFile signs the configs uploaded by the user.
+	SigningKeyFile string `json:"signing_key_file,omitempty"`
+	// BackupKeyFile is the identity replaced by the last rotation, kept
+	// until the rotation is confirmed.
+	BackupKeyFile string `json:"backup_key_file,omitempty"`
 }
 
 type Dolores struct {
 	Environments map[string]Environment `json:"environments"`
 }
 
-func (d *Dolores) AddEnvironment(env string, keyFile string, md Metadata) {
+func (d *Dolores) AddEnvironment(env, keyFile, signingKeyFile, userID string, md Metadata) {
 	if d.Environments == nil {
 		d.Environments = make(map[string]Environment)
 	}
 	d.Environments[env] = Environment{
-		Metadata: md,
-		KeyFile:  keyFile,
+		Metadata:       md,
+		KeyFile:        keyFile,
+		UserID:         userID,
+		SigningKeyFile: signingKeyFile,
+	}
+}
+
+// CopyEnvironment configures env with the cloud settings and signing key of
+// from. The identity is keyFile, sharing it would tie env to the rotations
+// of from.
+func (d *Dolores) CopyEnvironment(from, env, keyFile string) error {
+	if _, ok := d.Environments[env]; ok {
+		return fmt.Errorf("%s is already configured: %w", env, ErrInvalidDoloresConfig)
+	}
+	ec, ok := d.Environments[from]
+	if !ok {
+		return fmt.Errorf("%s: %w", from, ErrUnknownEnvironment)
+	}
+	md := ec.Metadata
+	md.Environment, md.CreatedAt = env, time.Now()
+	d.AddEnvironment(env, keyFile, ec.SigningKeyFile, ec.UserID, md)
+	return nil
+}
+
+// Names returns the configured environments in order.
+func (d Dolores) Names() []string {
+	names := make([]string, 0, len(d.Environments))
+	for name := range d.Environments {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
 func (d *Dolores) valid() error {
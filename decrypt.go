@@ -12,6 +12,12 @@ import (
 type DecryptConfig struct {
 	Key     string
 	KeyFile string
+	// Passphrase unlocks protected key files, it prompts on the terminal
+	// when unset.
+	Passphrase PassphraseFunc
+	// VerifyEnvelope checks the envelope before decrypting, it's called
+	// with nil for configs uploaded without one.
+	VerifyEnvelope func(env *Envelope) error
 }
 
 func (c *DecryptConfig) Identities() ([]age.Identity, error) {
@@ -25,6 +31,9 @@ func (c *DecryptConfig) Identities() ([]age.Identity, error) {
 	if c.KeyFile == "" {
 		return nil, ErrInvalidKeyFile
 	}
+	if c.Passphrase != nil {
+		return parseIdentities(c.KeyFile, c.Passphrase)
+	}
 	return ParseIdentities(c.KeyFile)
 }
 
@@ -49,11 +58,32 @@ func WithKeyFile(keyFile string) DecryptOpt {
 	}
 }
 
+func WithPassphrase(fn PassphraseFunc) DecryptOpt {
+	return func(c *DecryptConfig) {
+		c.Passphrase = fn
+	}
+}
+
 type Decryptor struct {
 	cfg DecryptConfig
 }
 
 func (d Decryptor) Decrypt(data []byte) ([]byte, error) {
+	rdr, err := d.DecryptFrom(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	result, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read: %w", err)
+	}
+	return result, nil
+}
+
+// DecryptFrom returns a reader of the plaintext, decrypting armored
+// ciphertext from src as it's read. When the config has an envelope, it's
+// verified against the content and reading fails on a mismatch.
+func (d Decryptor) DecryptFrom(src io.Reader) (io.Reader, error) {
 	if err := d.cfg.Valid(); err != nil {
 		return nil, err
 	}
@@ -61,23 +91,29 @@ func (d Decryptor) Decrypt(data []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return d.decryptWithIdentity(data, ids...)
-}
-
-func (d Decryptor) decryptWithIdentity(data []byte, ids ...age.Identity) ([]byte, error) {
-	dr := armor.NewReader(bytes.NewReader(data))
 	if len(ids) == 0 {
 		return nil, ErrInvalidIdentity
 	}
-	rdr, err := age.Decrypt(dr, ids...)
+	env, payload, err := ReadEnvelope(src)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt with age: %w", err)
+		return nil, err
 	}
-	result, err := io.ReadAll(rdr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read: %w", err)
+	if d.cfg.VerifyEnvelope != nil {
+		if err := d.cfg.VerifyEnvelope(env); err != nil {
+			return nil, err
+		}
 	}
-	return result, nil
+	decrypt := func(r io.Reader) (io.Reader, error) {
+		rdr, err := age.Decrypt(armor.NewReader(r), ids...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt with age: %w", err)
+		}
+		return rdr, nil
+	}
+	if env == nil {
+		return decrypt(payload)
+	}
+	return env.open(payload, decrypt)
 }
 
 func NewDecryptor(c *DecryptConfig, opts ...DecryptOpt) (Decryptor, error) {
@@ -1,6 +1,8 @@
 package dolores
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -43,7 +45,8 @@ func TestShouldEncryptLoadedFileSuccessfully(t *testing.T) {
 	result := string(d)
 	lines := strings.Split(result, "\n")
 	assert.NotEmpty(t, result)
-	assert.Equal(t, "-----BEGIN AGE ENCRYPTED FILE-----", lines[0])
+	assert.Equal(t, "-----BEGIN DOLORES ENVELOPE-----", lines[0])
+	assert.Contains(t, lines, "-----BEGIN AGE ENCRYPTED FILE-----")
 	assert.Equal(t, "-----END AGE ENCRYPTED FILE-----", lines[len(lines)-2])
 }
 
@@ -68,6 +71,24 @@ key4=something
 	assert.Equal(t, string(expectedPlain), string(result))
 }
 
+func TestShouldKeepCommentsWhenEncryptingFile(t *testing.T) {
+	enc, err := NewEncryptor(alicePubKey)
+	require.NoError(t, err, "creating encryptor failed")
+	data, err := LoadEnvFile("./testdata/sample.env")
+	require.NoError(t, err, "failed to load file")
+	d, err := enc.EncryptFile(data)
+	require.NoError(t, err, "failed to encrypt")
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: aliceKeyFile})
+	require.NoError(t, err, "creating decryptor failed")
+
+	result, err := dec.Decrypt(d)
+
+	require.NoError(t, err)
+	expectedPlain, err := os.ReadFile("./testdata/sample.env")
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedPlain), string(result))
+}
+
 func TestShouldDecryptFileWithKey(t *testing.T) {
 	data, err := os.ReadFile("./testdata/sample.age")
 	require.NoError(t, err)
@@ -85,3 +106,20 @@ key4=something
 `
 	assert.Equal(t, string(expectedPlain), string(result))
 }
+
+func TestShouldStreamEncryptionAndDecryption(t *testing.T) {
+	enc, err := NewEncryptor(alicePubKey)
+	require.NoError(t, err)
+	plain := strings.Repeat("-----BEGIN CERTIFICATE-----\n", 1<<12)
+	buf := new(bytes.Buffer)
+	require.NoError(t, enc.EncryptTo(buf, strings.NewReader(plain)))
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: aliceKeyFile})
+	require.NoError(t, err)
+
+	rdr, err := dec.DecryptFrom(buf)
+	require.NoError(t, err)
+	result, err := io.ReadAll(rdr)
+
+	require.NoError(t, err)
+	assert.Equal(t, plain, string(result))
+}
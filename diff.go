@@ -0,0 +1,53 @@
+package dolores
+
+import "bytes"
+
+// Diff lists the keys that differ between two sets of variables.
+type Diff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffVariables compares the variables by key, keeping the order in which
+// the keys appear. When a key is repeated the last value wins.
+func DiffVariables(before, after []Variable) Diff {
+	old := lastValues(before)
+	cur := lastValues(after)
+	diff := Diff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	seen := make(map[string]bool)
+	for _, v := range after {
+		key := string(v.Key)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, key)
+		case !bytes.Equal(prev, cur[key]):
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for _, v := range before {
+		key := string(v.Key)
+		if _, ok := cur[key]; !ok && !seen[key] {
+			seen[key] = true
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	return diff
+}
+
+func lastValues(vars []Variable) map[string][]byte {
+	res := make(map[string][]byte, len(vars))
+	for _, v := range vars {
+		res[string(v.Key)] = v.Value
+	}
+	return res
+}
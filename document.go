@@ -0,0 +1,134 @@
+package dolores
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type NodeKind int
+
+const (
+	BlankNode NodeKind = iota
+	CommentNode
+	VariableNode
+)
+
+// Node is a single logical line of an env file. Raw holds the original text
+// including the line terminator, so unchanged nodes are written back exactly
+// as they were read. Prefix and Suffix hold the text around the value, like
+// an export keyword or a trailing comment, kept when the value changes.
+type Node struct {
+	Kind     NodeKind
+	Raw      []byte
+	Variable Variable
+	Quote    byte
+	Prefix   []byte
+	Suffix   []byte
+}
+
+func (n Node) Bytes() []byte {
+	if n.Raw != nil {
+		return n.Raw
+	}
+	if n.Kind != VariableNode {
+		return []byte{'\n'}
+	}
+	res := append([]byte{}, n.Prefix...)
+	if n.Prefix == nil {
+		res = append(res, n.Variable.Key...)
+		res = append(res, '=')
+	}
+	res = append(res, quoteValueWith(n.Variable.Value, n.Quote)...)
+	if n.Suffix == nil {
+		return append(res, '\n')
+	}
+	return append(res, n.Suffix...)
+}
+
+// Document keeps comments, blank lines, ordering and quoting of an env file
+// alongside its variables.
+type Document struct {
+	Nodes []Node
+}
+
+func (d Document) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	for _, n := range d.Nodes {
+		buf.Write(n.Bytes())
+	}
+	return buf.Bytes()
+}
+
+func (d Document) Variables() []Variable {
+	vars := make([]Variable, 0, len(d.Nodes))
+	for _, n := range d.Nodes {
+		if n.Kind == VariableNode {
+			vars = append(vars, n.Variable)
+		}
+	}
+	return vars
+}
+
+// Set assigns the value to every assignment of the key, keeping its quote
+// style, export keyword and comment, or appends the key when it isn't set
+// yet.
+func (d *Document) Set(key string, value []byte) error {
+	if err := ValidKey(key); err != nil {
+		return err
+	}
+	found := false
+	for i, n := range d.Nodes {
+		if n.Kind == VariableNode && string(n.Variable.Key) == key {
+			d.Nodes[i].Variable.Value = bytes.Clone(value)
+			d.Nodes[i].Raw = nil
+			found = true
+		}
+	}
+	if found {
+		return nil
+	}
+	if last := len(d.Nodes) - 1; last >= 0 && !bytes.HasSuffix(d.Nodes[last].Bytes(), []byte{'\n'}) {
+		d.Nodes[last].Raw = append(bytes.Clone(d.Nodes[last].Bytes()), '\n')
+	}
+	d.Nodes = append(d.Nodes, Node{Kind: VariableNode, Variable: Variable{Key: []byte(key), Value: bytes.Clone(value)}})
+	return nil
+}
+
+// Unset removes every assignment of the key, reporting whether it was set.
+func (d *Document) Unset(key string) bool {
+	nodes := make([]Node, 0, len(d.Nodes))
+	for _, n := range d.Nodes {
+		if n.Kind != VariableNode || string(n.Variable.Key) != key {
+			nodes = append(nodes, n)
+		}
+	}
+	removed := len(nodes) != len(d.Nodes)
+	d.Nodes = nodes
+	return removed
+}
+
+// ValidKey checks the key can be written as a dotenv variable name.
+func ValidKey(key string) error {
+	if key == "" || !isKeyStart(key[0]) {
+		return fmt.Errorf("%q: %w", key, ErrInvalidKey)
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("%q: %w", key, ErrInvalidKey)
+		}
+	}
+	return nil
+}
+
+func parseDocument(data []byte) (Document, error) {
+	p := &envParser{data: data}
+	var doc Document
+	for !p.eof() {
+		n, err := p.node()
+		if err != nil {
+			return Document{}, err
+		}
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	return doc, nil
+}
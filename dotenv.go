@@ -0,0 +1,296 @@
+package dolores
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type ParseError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrInvalidFormat
+}
+
+type envParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *envParser) errorAt(offset int, format string, args ...any) error {
+	line, col := 1, 1
+	for _, c := range p.data[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+			continue
+		}
+		col++
+	}
+	return &ParseError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *envParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *envParser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *envParser) skipBlank() {
+	for !p.eof() && isBlank(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *envParser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.pos++
+	}
+}
+
+// endLine consumes optional trailing whitespace and comment after a value.
+func (p *envParser) endLine() error {
+	p.skipBlank()
+	if p.atLineEnd() || p.peek() == '#' {
+		p.skipLine()
+		return nil
+	}
+	return p.errorAt(p.pos, "unexpected character %q after value", p.peek())
+}
+
+// node parses the next logical line, which may span multiple physical
+// lines when it holds a quoted multiline value.
+func (p *envParser) node() (Node, error) {
+	start := p.pos
+	p.skipBlank()
+	switch {
+	case p.atLineEnd():
+		p.skipLine()
+		return Node{Kind: BlankNode, Raw: p.data[start:p.pos]}, nil
+	case p.peek() == '#':
+		p.skipLine()
+		return Node{Kind: CommentNode, Raw: p.data[start:p.pos]}, nil
+	}
+	return p.assignment(start)
+}
+
+func (p *envParser) atLineEnd() bool {
+	if p.eof() || p.peek() == '\n' {
+		return true
+	}
+	return p.peek() == '\r' && (p.pos+1 == len(p.data) || p.data[p.pos+1] == '\n')
+}
+
+// revive:disable function-length
+func (p *envParser) assignment(line int) (Node, error) {
+	p.skipExport()
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.pos++
+	}
+	key := p.data[start:p.pos]
+	if len(key) == 0 {
+		return Node{}, p.errorAt(p.pos, "expected variable name")
+	}
+	if !isKeyStart(key[0]) {
+		return Node{}, p.errorAt(start, "invalid variable name %q", key)
+	}
+	p.skipBlank()
+	if p.eof() || p.peek() != '=' {
+		return Node{}, p.errorAt(p.pos, "expected '=' after %q", key)
+	}
+	p.pos++
+	p.skipBlank()
+	var quote byte
+	if !p.eof() && (p.peek() == '\'' || p.peek() == '"') {
+		quote = p.peek()
+	}
+	valueStart := p.pos
+	value, valueEnd, err := p.value()
+	if err != nil {
+		return Node{}, err
+	}
+	return Node{
+		Kind:     VariableNode,
+		Raw:      p.data[line:p.pos],
+		Variable: Variable{Key: bytes.Clone(key), Value: value},
+		Quote:    quote,
+		Prefix:   p.data[line:valueStart],
+		Suffix:   p.data[valueEnd:p.pos],
+	}, nil
+}
+
+func (p *envParser) skipExport() {
+	const export = "export"
+	rest := p.data[p.pos:]
+	if !bytes.HasPrefix(rest, []byte(export)) || len(rest) <= len(export) || !isBlank(rest[len(export)]) {
+		return
+	}
+	p.pos += len(export)
+	p.skipBlank()
+}
+
+// value returns the value with the offset it ends at, before any trailing
+// comment.
+func (p *envParser) value() ([]byte, int, error) {
+	if p.eof() {
+		return []byte{}, p.pos, nil
+	}
+	var value []byte
+	var err error
+	switch p.peek() {
+	case '\'':
+		value, err = p.singleQuoted()
+	case '"':
+		value, err = p.doubleQuoted()
+	default:
+		start := p.pos
+		value = p.unquoted()
+		return value, start + len(value), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	end := p.pos
+	return value, end, p.endLine()
+}
+
+func (p *envParser) unquoted() []byte {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		if p.peek() == '#' && p.pos > start && isBlank(p.data[p.pos-1]) {
+			break
+		}
+		p.pos++
+	}
+	value := bytes.TrimRight(p.data[start:p.pos], " \t\r")
+	p.skipLine()
+	return bytes.Clone(value)
+}
+
+func (p *envParser) singleQuoted() ([]byte, error) {
+	open := p.pos
+	p.pos++
+	end := bytes.IndexByte(p.data[p.pos:], '\'')
+	if end < 0 {
+		return nil, p.errorAt(open, "unterminated single quoted value")
+	}
+	value := bytes.Clone(p.data[p.pos : p.pos+end])
+	p.pos += end + 1
+	return value, nil
+}
+
+func (p *envParser) doubleQuoted() ([]byte, error) {
+	open := p.pos
+	p.pos++
+	value := make([]byte, 0)
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == '"':
+			return value, nil
+		case c == '\\' && !p.eof():
+			value = append(value, unescape(p.peek())...)
+			p.pos++
+		default:
+			value = append(value, c)
+		}
+	}
+	return nil, p.errorAt(open, "unterminated double quoted value")
+}
+
+func unescape(c byte) []byte {
+	switch c {
+	case 'n':
+		return []byte{'\n'}
+	case 'r':
+		return []byte{'\r'}
+	case 't':
+		return []byte{'\t'}
+	case '\\', '"', '$', '\'', '`':
+		return []byte{c}
+	default:
+		return []byte{'\\', c}
+	}
+}
+
+// quoteValue returns the value as it should appear on the right hand side
+// of an assignment, quoting and escaping only when required.
+func quoteValue(value []byte) []byte {
+	for _, c := range value {
+		if !isBareChar(c) {
+			return doubleQuote(value)
+		}
+	}
+	return bytes.Clone(value)
+}
+
+// quoteValueWith renders the value using the preferred quote style, falling
+// back to double quotes when a single quoted literal cannot hold the value.
+func quoteValueWith(value []byte, quote byte) []byte {
+	switch {
+	case quote == '\'' && bytes.IndexByte(value, '\'') < 0:
+		res := append([]byte{'\''}, value...)
+		return append(res, '\'')
+	case quote == '"':
+		return doubleQuote(value)
+	default:
+		return quoteValue(value)
+	}
+}
+
+func doubleQuote(value []byte) []byte {
+	res := make([]byte, 0, len(value)+2)
+	res = append(res, '"')
+	for _, c := range value {
+		switch c {
+		case '\n':
+			res = append(res, '\\', 'n')
+		case '\r':
+			res = append(res, '\\', 'r')
+		case '\t':
+			res = append(res, '\\', 't')
+		case '\\', '"', '$', '`':
+			res = append(res, '\\', c)
+		default:
+			res = append(res, c)
+		}
+	}
+	return append(res, '"')
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isKeyStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isKeyChar(c byte) bool {
+	return isKeyStart(c) || (c >= '0' && c <= '9') || c == '.' || c == '-'
+}
+
+func isBareChar(c byte) bool {
+	if isKeyChar(c) {
+		return true
+	}
+	switch c {
+	case '/', ':', '@', '+', ',', '=', '?', '&', '%', '~', '^', '!', '*':
+		return true
+	}
+	return false
+}
@@ -2,7 +2,13 @@ package dolores
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	"filippo.io/age"
 	"filippo.io/age/armor"
@@ -10,37 +16,114 @@ import (
 
 type Encryptor struct {
 	recipients []age.Recipient
+	// Author is recorded in the envelope of encrypted configs.
+	Author string
+	// SigningKey signs the envelope when it's set.
+	SigningKey *SigningKey
+	// NeedsRotation carries the rotation marks over when a config is
+	// re-encrypted without changing its values.
+	NeedsRotation []string
+	fingerprints  []string
 }
 
 func (e *Encryptor) Encrypt(vars []Variable) ([]byte, error) {
+	data := make([]byte, 0)
+	for _, v := range vars {
+		data = append(data, v.Data()...)
+	}
+	return e.encrypt(data)
+}
+
+// EncryptFile encrypts the whole file, keeping comments and formatting.
+func (e *Encryptor) EncryptFile(ef *EnvFile) ([]byte, error) {
+	return e.encrypt(ef.Payload())
+}
+
+func (e *Encryptor) encrypt(data []byte) ([]byte, error) {
 	buf := &bytes.Buffer{}
-	arm := armor.NewWriter(buf)
+	env, err := e.seal(buf, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return append(env.Bytes(), buf.Bytes()...), nil
+}
+
+// EncryptTo streams the enveloped ciphertext of src into dst. The envelope
+// holds the hash of the ciphertext, so it's spooled to a temporary file
+// instead of memory.
+func (e *Encryptor) EncryptTo(dst io.Writer, src io.Reader) error {
+	spool, err := os.CreateTemp("", "dolores-*.age")
+	if err != nil {
+		return fmt.Errorf("error creating spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	env, err := e.seal(spool, src)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error reading spool file: %w", err)
+	}
+	if _, err := dst.Write(env.Bytes()); err != nil {
+		return fmt.Errorf("error writing envelope: %w", err)
+	}
+	if _, err := io.Copy(dst, spool); err != nil {
+		return fmt.Errorf("error writing data: %w", err)
+	}
+	return nil
+}
+
+// seal writes the armored ciphertext into w and returns its envelope.
+func (e *Encryptor) seal(w io.Writer, src io.Reader) (Envelope, error) {
+	env := Envelope{
+		Version:       EnvelopeVersion,
+		Author:        e.Author,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+		Tool:          Version,
+		Recipients:    e.fingerprints,
+		NeedsRotation: e.NeedsRotation,
+	}
+	h := sha256.New()
+	plain := io.MultiReader(strings.NewReader(env.marker()), src)
+	if err := e.encryptArmored(io.MultiWriter(w, h), plain); err != nil {
+		return Envelope{}, err
+	}
+	env.ContentHash = hashPrefix + hex.EncodeToString(h.Sum(nil))
+	if e.SigningKey != nil {
+		e.SigningKey.sign(&env)
+	}
+	return env, nil
+}
+
+func (e *Encryptor) encryptArmored(dst io.Writer, src io.Reader) error {
+	arm := armor.NewWriter(dst)
 	w, err := age.Encrypt(arm, e.recipients...)
 	if err != nil {
-		return nil, fmt.Errorf("error encrypting: %w", err)
+		return fmt.Errorf("error encrypting: %w", err)
 	}
-	for _, v := range vars {
-		if _, err := w.Write(v.Data()); err != nil {
-			return nil, fmt.Errorf("error writing data: %w", err)
-		}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("error writing data: %w", err)
 	}
 	if err := w.Close(); err != nil {
-		return nil, fmt.Errorf("error closing writer: %w", err)
+		return fmt.Errorf("error closing writer: %w", err)
 	}
 	if err := arm.Close(); err != nil {
-		return nil, fmt.Errorf("error closing arm writer: %w", err)
+		return fmt.Errorf("error closing arm writer: %w", err)
 	}
-	return buf.Bytes(), nil
+	return nil
 }
 
 func NewEncryptor(keys ...string) (*Encryptor, error) {
 	recps := make([]age.Recipient, len(keys))
+	fps := make([]string, len(keys))
 	for i, key := range keys {
-		recp, err := age.ParseX25519Recipient(key)
+		recp, err := ParseRecipient(key)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing %d key: %w", i, err)
 		}
 		recps[i] = recp
+		fps[i] = Fingerprint(key)
 	}
-	return &Encryptor{recipients: recps}, nil
+	return &Encryptor{recipients: recps, fingerprints: fps}, nil
 }
@@ -0,0 +1,234 @@
+package dolores
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	EnvelopeVersion = 1
+
+	envelopeHeader = "-----BEGIN DOLORES ENVELOPE-----"
+	envelopeFooter = "-----END DOLORES ENVELOPE-----"
+	envelopeMarker = "#dolores:envelope="
+	hashPrefix     = "sha256:"
+)
+
+var ErrEnvelopeMismatch = errors.New("envelope doesn't match the encrypted content")
+
+// Version is recorded in the envelope of uploaded configs, the cli sets it
+// to the release version.
+var Version = "dev"
+
+// Envelope is the plain header stored in front of the armored age payload,
+// so a config can be inspected without a private key.
+type Envelope struct {
+	Version     int
+	Author      string
+	CreatedAt   time.Time
+	Tool        string
+	Recipients  []string
+	ContentHash string
+	// Signer is the fingerprint of the signing key, the signature covers
+	// the digest and the content hash.
+	Signer    string
+	Signature string
+	// NeedsRotation lists the keys flagged after someone who could read
+	// them left, it isn't signed so it can be updated without re-encrypting.
+	NeedsRotation []string
+}
+
+// Digest covers the fields describing how the config was produced. It's
+// encrypted along with the content, binding the header to it.
+func (e Envelope) Digest() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "version:%d\nauthor:%s\ncreated_at:%s\ntool:%s\n",
+		e.Version, e.Author, e.CreatedAt.UTC().Format(time.RFC3339), e.Tool)
+	for _, r := range e.Recipients {
+		fmt.Fprintf(h, "recipient:%s\n", r)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (e Envelope) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(envelopeHeader + "\n")
+	fmt.Fprintf(buf, "version: %d\n", e.Version)
+	fmt.Fprintf(buf, "author: %s\n", e.Author)
+	fmt.Fprintf(buf, "created_at: %s\n", e.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(buf, "tool: %s\n", e.Tool)
+	for _, r := range e.Recipients {
+		fmt.Fprintf(buf, "recipient: %s\n", r)
+	}
+	fmt.Fprintf(buf, "content_hash: %s\n", e.ContentHash)
+	if e.Signature != "" {
+		fmt.Fprintf(buf, "signer: %s\n", e.Signer)
+		fmt.Fprintf(buf, "signature: %s\n", e.Signature)
+	}
+	for _, k := range e.NeedsRotation {
+		fmt.Fprintf(buf, "needs_rotation: %s\n", k)
+	}
+	buf.WriteString(envelopeFooter + "\n")
+	return buf.Bytes()
+}
+
+func (e Envelope) marker() string {
+	return envelopeMarker + e.Digest() + "\n"
+}
+
+// revive:disable cyclomatic
+func (e *Envelope) set(key, value string) error {
+	switch key {
+	case "version":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid envelope version %s: %w", value, ErrInvalidFormat)
+		}
+		e.Version = v
+	case "author":
+		e.Author = value
+	case "created_at":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid envelope time %s: %w", value, ErrInvalidFormat)
+		}
+		e.CreatedAt = t
+	case "tool":
+		e.Tool = value
+	case "recipient":
+		e.Recipients = append(e.Recipients, value)
+	case "content_hash":
+		e.ContentHash = value
+	case "signer":
+		e.Signer = value
+	case "signature":
+		e.Signature = value
+	case "needs_rotation":
+		e.NeedsRotation = append(e.NeedsRotation, value)
+	}
+	return nil
+}
+
+// ReadEnvelope parses the envelope in front of an encrypted config and
+// returns a reader of the armored payload. Configs uploaded before envelopes
+// were introduced return a nil envelope.
+func ReadEnvelope(r io.Reader) (*Envelope, io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(envelopeHeader))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if string(head) != envelopeHeader {
+		return nil, br, nil
+	}
+	env := new(Envelope)
+	for first := true; ; first = false {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("unterminated envelope: %w", ErrInvalidFormat)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if first {
+			continue
+		}
+		if line == envelopeFooter {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid envelope line %q: %w", line, ErrInvalidFormat)
+		}
+		if err := env.set(key, strings.TrimSpace(value)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if env.Version > EnvelopeVersion {
+		return nil, nil, fmt.Errorf("envelope version %d isn't supported, upgrade dolores: %w", env.Version, ErrInvalidFormat)
+	}
+	return env, br, nil
+}
+
+// VerifyContent hashes the rest of the payload and checks it against the
+// content hash of the envelope.
+func (e Envelope) VerifyContent(payload io.Reader) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, payload); err != nil {
+		return fmt.Errorf("failed to read payload: %w", err)
+	}
+	return e.checkHash(h)
+}
+
+func (e Envelope) checkHash(h hash.Hash) error {
+	if hashPrefix+hex.EncodeToString(h.Sum(nil)) != e.ContentHash {
+		return fmt.Errorf("content hash: %w", ErrEnvelopeMismatch)
+	}
+	return nil
+}
+
+// open verifies the content of the envelope while the plaintext is read:
+// the marker binding the header is checked first and the ciphertext hash
+// once the plaintext is fully read.
+func (e Envelope) open(payload io.Reader, decrypt func(io.Reader) (io.Reader, error)) (io.Reader, error) {
+	h := sha256.New()
+	src := io.TeeReader(payload, h)
+	plain, err := decrypt(src)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(plain)
+	marker, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	if marker != e.marker() {
+		return nil, fmt.Errorf("header: %w", ErrEnvelopeMismatch)
+	}
+	return &verifyingReader{r: br, src: src, env: e, h: h}, nil
+}
+
+type verifyingReader struct {
+	r   io.Reader
+	src io.Reader
+	env Envelope
+	h   hash.Hash
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	if !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	// armor stops at its footer, the trailing bytes still count
+	if _, err := io.Copy(io.Discard, v.src); err != nil {
+		return n, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if err := v.env.checkHash(v.h); err != nil {
+		return n, err
+	}
+	return n, io.EOF
+}
+
+// Fingerprint identifies a recipient public key without exposing it, ssh
+// keys are reduced to their key type and data so comments don't matter.
+func Fingerprint(key string) string {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "ssh-") {
+		if pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err == nil {
+			return ssh.FingerprintSHA256(pub)
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
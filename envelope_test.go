@@ -0,0 +1,77 @@
+package dolores
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptWithEnvelope(t *testing.T) []byte {
+	t.Helper()
+	enc, err := NewEncryptor(alicePubKey, bobPubKey)
+	require.NoError(t, err)
+	enc.Author = "alice"
+	buf := new(bytes.Buffer)
+	require.NoError(t, enc.EncryptTo(buf, strings.NewReader("KEY=value\n")))
+	return buf.Bytes()
+}
+
+func TestShouldReadEnvelopeWithoutPrivateKey(t *testing.T) {
+	data := encryptWithEnvelope(t)
+
+	env, payload, err := ReadEnvelope(bytes.NewReader(data))
+
+	require.NoError(t, err)
+	require.NotNil(t, env)
+	assert.Equal(t, EnvelopeVersion, env.Version)
+	assert.Equal(t, "alice", env.Author)
+	assert.Equal(t, []string{Fingerprint(alicePubKey), Fingerprint(bobPubKey)}, env.Recipients)
+	assert.False(t, env.CreatedAt.IsZero())
+	assert.True(t, strings.HasPrefix(env.ContentHash, "sha256:"))
+	assert.NoError(t, env.VerifyContent(payload))
+}
+
+func TestShouldDecryptConfigWithEnvelope(t *testing.T) {
+	data := encryptWithEnvelope(t)
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: bobKeyFile})
+	require.NoError(t, err)
+
+	result, err := dec.Decrypt(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", string(result))
+}
+
+func TestShouldRejectTamperedEnvelope(t *testing.T) {
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: aliceKeyFile})
+	require.NoError(t, err)
+	tests := map[string]func([]byte) []byte{
+		"author": func(d []byte) []byte {
+			return bytes.Replace(d, []byte("author: alice"), []byte("author: mallory"), 1)
+		},
+		"content hash": func(d []byte) []byte {
+			return bytes.Replace(d, []byte("content_hash: sha256:"), []byte("content_hash: sha256:00"), 1)
+		},
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			rdr, err := dec.DecryptFrom(bytes.NewReader(tamper(encryptWithEnvelope(t))))
+			if err == nil {
+				_, err = io.ReadAll(rdr)
+			}
+
+			assert.ErrorIs(t, err, ErrEnvelopeMismatch)
+		})
+	}
+}
+
+func TestShouldFingerprintSSHKeysIgnoringComment(t *testing.T) {
+	_, pubKey := writeSSHKey(t, "")
+
+	assert.Equal(t, Fingerprint(pubKey), Fingerprint(pubKey+" alice@laptop\n"))
+	assert.True(t, strings.HasPrefix(Fingerprint(alicePubKey), "SHA256:"))
+}
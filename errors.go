@@ -7,4 +7,6 @@ var (
 	ErrInvalidFormat       = errors.New("invalid file content format")
 	ErrInvalidIdentity     = errors.New("invalid identity")
 	ErrInvalidConfiguraion = errors.New("invalid configuration")
+	ErrInvalidKey          = errors.New("invalid variable name")
+	ErrNotDotenv           = errors.New("only dotenv configs can be modified by key")
 )
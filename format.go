@@ -0,0 +1,158 @@
+package dolores
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatDotenv     Format = "dotenv"
+	FormatJSON       Format = "json"
+	FormatYAML       Format = "yaml"
+	FormatTOML       Format = "toml"
+	FormatProperties Format = "properties"
+)
+
+// KeySeparator joins nested keys of structured formats, so that
+// {"db": {"host": "x"}} is exposed as DB__HOST.
+const KeySeparator = "__"
+
+const payloadFormatPrefix = "#dolores:format="
+
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatDotenv, FormatJSON, FormatYAML, FormatTOML, FormatProperties:
+		return f, nil
+	case "env", "":
+		return FormatDotenv, nil
+	case "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("unknown format %s: %w", name, ErrInvalidFormat)
+}
+
+// DetectFormat guesses the format from the file extension, defaulting to dotenv.
+func DetectFormat(fname string) Format {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".properties":
+		return FormatProperties
+	}
+	return FormatDotenv
+}
+
+// Ext returns the file extension commonly used for the format.
+func (f Format) Ext() string {
+	if f == FormatDotenv || f == "" {
+		return ".env"
+	}
+	return "." + string(f)
+}
+
+// FlatKey builds the variable name for a nested key path.
+func FlatKey(path ...string) string {
+	segs := make([]string, len(path))
+	for i, p := range path {
+		segs[i] = strings.Map(func(r rune) rune {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(p))
+	}
+	return strings.Join(segs, KeySeparator)
+}
+
+func parseStructured(format Format, data []byte) ([]Variable, error) {
+	var tree any
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&tree); err != nil {
+			return nil, fmt.Errorf("invalid json: %w: %w", ErrInvalidFormat, err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w: %w", ErrInvalidFormat, err)
+		}
+	case FormatTOML:
+		m := make(map[string]any)
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid toml: %w: %w", ErrInvalidFormat, err)
+		}
+		tree = m
+	case FormatProperties:
+		return parseProperties(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %s: %w", format, ErrInvalidFormat)
+	}
+	vars := make([]Variable, 0)
+	flatten(nil, tree, &vars)
+	return vars, nil
+}
+
+// revive:disable cyclomatic
+func flatten(path []string, value any, vars *[]Variable) {
+	switch val := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(append(path[:len(path):len(path)], k), val[k], vars)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(val))
+		for k, v := range val {
+			m[fmt.Sprint(k)] = v
+		}
+		flatten(path, m, vars)
+	case []map[string]any:
+		for i, v := range val {
+			flatten(append(path[:len(path):len(path)], strconv.Itoa(i)), v, vars)
+		}
+	case []any:
+		for i, v := range val {
+			flatten(append(path[:len(path):len(path)], strconv.Itoa(i)), v, vars)
+		}
+	default:
+		if len(path) == 0 {
+			return
+		}
+		*vars = append(*vars, Variable{Key: []byte(FlatKey(path...)), Value: []byte(scalar(val))})
+	}
+}
+
+func scalar(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
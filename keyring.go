@@ -0,0 +1,228 @@
+package dolores
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	KeyringVersion = 1
+
+	keyringContext = "dolores-keyring-v1\n"
+)
+
+var (
+	ErrNotInKeyring     = errors.New("user isn't in the keyring")
+	ErrUntrustedKeyring = errors.New("keyring isn't signed by a trusted member")
+	ErrKeyringRollback  = errors.New("keyring is older than the pinned one")
+)
+
+// KeyringEntry is a team member whose keys were approved by another member.
+type KeyringEntry struct {
+	UserID     string    `json:"user_id"`
+	PublicKey  string    `json:"public_key"`
+	SigningKey string    `json:"signing_key"`
+	AddedBy    string    `json:"added_by"`
+	AddedAt    time.Time `json:"added_at"`
+	ApprovedBy string    `json:"approved_by"`
+}
+
+// Keyring is the signed manifest of the keys configs are encrypted to, so
+// a key written next to it isn't trusted until a member approves it.
+type Keyring struct {
+	Version   int            `json:"version"`
+	Serial    int            `json:"serial"`
+	Entries   []KeyringEntry `json:"entries"`
+	UpdatedBy string         `json:"updated_by"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Signer    string         `json:"signer,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+}
+
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := new(Keyring)
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+	if k.Version > KeyringVersion {
+		return nil, fmt.Errorf("keyring version %d isn't supported, upgrade dolores: %w", k.Version, ErrInvalidFormat)
+	}
+	return k, nil
+}
+
+func (k Keyring) Bytes() ([]byte, error) {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keyring: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func (k Keyring) Entry(userID string) (KeyringEntry, bool) {
+	for _, e := range k.Entries {
+		if e.UserID == userID {
+			return e, true
+		}
+	}
+	return KeyringEntry{}, false
+}
+
+// Add stores the entry, replacing the keys of an existing user.
+func (k *Keyring) Add(entry KeyringEntry) {
+	for i, e := range k.Entries {
+		if e.UserID == entry.UserID {
+			k.Entries[i] = entry
+			return
+		}
+	}
+	k.Entries = append(k.Entries, entry)
+}
+
+// Remove drops the user from the keyring, reporting whether it was in it.
+func (k *Keyring) Remove(userID string) bool {
+	for i, e := range k.Entries {
+		if e.UserID == userID {
+			k.Entries = append(k.Entries[:i], k.Entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (k Keyring) Recipients() []string {
+	keys := make([]string, len(k.Entries))
+	for i, e := range k.Entries {
+		keys[i] = e.PublicKey
+	}
+	return keys
+}
+
+func (k Keyring) Signers() []Signer {
+	signers := make([]Signer, 0, len(k.Entries))
+	for _, e := range k.Entries {
+		if e.SigningKey != "" {
+			signers = append(signers, Signer{ID: e.UserID, PublicKey: e.SigningKey})
+		}
+	}
+	return signers
+}
+
+// Sign records the update and signs the keyring, the signer has to be a
+// member of it.
+func (k *Keyring) Sign(userID string, key *SigningKey) error {
+	entry, ok := k.Entry(userID)
+	if !ok || entry.SigningKey != key.Public() {
+		return fmt.Errorf("%s: %w", userID, ErrNotInKeyring)
+	}
+	k.Version = KeyringVersion
+	k.Serial++
+	k.UpdatedBy = userID
+	k.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	msg, err := k.signedMessage()
+	if err != nil {
+		return err
+	}
+	k.Signer = Fingerprint(key.Public())
+	k.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key.key, msg))
+	return nil
+}
+
+// Verify checks the keyring is signed by one of the trusted signers, who
+// is also the recorded updater.
+func (k Keyring) Verify(trusted []Signer) error {
+	sig, err := base64.StdEncoding.DecodeString(k.Signature)
+	if err != nil || k.Signature == "" {
+		return fmt.Errorf("missing signature: %w", ErrUntrustedKeyring)
+	}
+	msg, err := k.signedMessage()
+	if err != nil {
+		return err
+	}
+	for _, s := range trusted {
+		if Fingerprint(s.PublicKey) != k.Signer || s.ID != k.UpdatedBy {
+			continue
+		}
+		pub, err := parseSignerKey(s.PublicKey)
+		if err != nil {
+			return err
+		}
+		if ed25519.Verify(pub, msg, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signer %s: %w", k.Signer, ErrUntrustedKeyring)
+}
+
+func (k Keyring) signedMessage() ([]byte, error) {
+	k.Signer, k.Signature = "", ""
+	data, err := json.Marshal(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keyring: %w", err)
+	}
+	return append([]byte(keyringContext), data...), nil
+}
+
+// TrustKeyring checks the keyring against the copy pinned in pinFile and
+// pins it. The first keyring seen is trusted as is, later ones have to be
+// signed by a member of the pinned keyring. It reports whether the keyring
+// was pinned for the first time.
+func TrustKeyring(pinFile string, k *Keyring) (bool, error) {
+	if err := k.Verify(k.Signers()); err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(pinFile)
+	if os.IsNotExist(err) {
+		return true, pinKeyring(pinFile, k)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read pinned keyring: %w", err)
+	}
+	pinned, err := ParseKeyring(data)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case k.Serial < pinned.Serial:
+		return false, fmt.Errorf("serial %d, pinned %d: %w", k.Serial, pinned.Serial, ErrKeyringRollback)
+	case k.Serial == pinned.Serial:
+		if k.Signature != pinned.Signature {
+			return false, fmt.Errorf("keyring differs from the pinned one with serial %d: %w", k.Serial, ErrUntrustedKeyring)
+		}
+		return false, nil
+	}
+	if err := k.Verify(pinned.Signers()); err != nil {
+		return false, err
+	}
+	return false, pinKeyring(pinFile, k)
+}
+
+func pinKeyring(pinFile string, k *Keyring) error {
+	data, err := k.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(pinFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to pin keyring: %w", err)
+	}
+	return nil
+}
+
+// Unapproved returns the keys that aren't in the keyring.
+func (k Keyring) Unapproved(keys []string) []string {
+	known := make(map[string]bool, len(k.Entries))
+	for _, e := range k.Entries {
+		known[Fingerprint(e.PublicKey)] = true
+	}
+	result := make([]string, 0)
+	for _, key := range keys {
+		if strings.TrimSpace(key) != "" && !known[Fingerprint(key)] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
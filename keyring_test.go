@@ -0,0 +1,94 @@
+package dolores
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T) (*Keyring, *SigningKey) {
+	t.Helper()
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	k := new(Keyring)
+	k.Add(KeyringEntry{UserID: "alice", PublicKey: alicePubKey, SigningKey: key.Public(), AddedBy: "alice", ApprovedBy: "alice"})
+	require.NoError(t, k.Sign("alice", key))
+	return k, key
+}
+
+func reparse(t *testing.T, k *Keyring) *Keyring {
+	t.Helper()
+	data, err := k.Bytes()
+	require.NoError(t, err)
+	parsed, err := ParseKeyring(data)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestShouldVerifySignedKeyring(t *testing.T) {
+	k, _ := newTestKeyring(t)
+	parsed := reparse(t, k)
+
+	assert.NoError(t, parsed.Verify(parsed.Signers()))
+	assert.Equal(t, []string{alicePubKey}, parsed.Recipients())
+	assert.Equal(t, []string{bobPubKey}, parsed.Unapproved([]string{alicePubKey, bobPubKey}))
+
+	parsed.Entries[0].PublicKey = bobPubKey
+	assert.ErrorIs(t, parsed.Verify(parsed.Signers()), ErrUntrustedKeyring)
+}
+
+func TestShouldRefuseSigningByNonMember(t *testing.T) {
+	k, _ := newTestKeyring(t)
+	other, err := GenerateSigningKey()
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, k.Sign("mallory", other), ErrNotInKeyring)
+	assert.ErrorIs(t, k.Sign("alice", other), ErrNotInKeyring)
+}
+
+func TestShouldPinKeyringOnFirstUse(t *testing.T) {
+	pin := filepath.Join(t.TempDir(), "staging.keyring")
+	k, key := newTestKeyring(t)
+
+	first, err := TrustKeyring(pin, reparse(t, k))
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	k.Add(KeyringEntry{UserID: "bob", PublicKey: bobPubKey, AddedBy: "bob", ApprovedBy: "alice"})
+	require.NoError(t, k.Sign("alice", key))
+	first, err = TrustKeyring(pin, reparse(t, k))
+	require.NoError(t, err)
+	assert.False(t, first)
+}
+
+func TestShouldRefuseKeyringNotSignedByPinnedMember(t *testing.T) {
+	pin := filepath.Join(t.TempDir(), "staging.keyring")
+	k, _ := newTestKeyring(t)
+	_, err := TrustKeyring(pin, reparse(t, k))
+	require.NoError(t, err)
+
+	forged, _ := newTestKeyring(t)
+	forged.Serial = k.Serial
+	malloryKey, err := GenerateSigningKey()
+	require.NoError(t, err)
+	forged.Add(KeyringEntry{UserID: "mallory", PublicKey: bobPubKey, SigningKey: malloryKey.Public()})
+	require.NoError(t, forged.Sign("mallory", malloryKey))
+
+	_, err = TrustKeyring(pin, reparse(t, forged))
+	assert.ErrorIs(t, err, ErrUntrustedKeyring)
+}
+
+func TestShouldRefuseKeyringRollback(t *testing.T) {
+	pin := filepath.Join(t.TempDir(), "staging.keyring")
+	k, key := newTestKeyring(t)
+	older := reparse(t, k)
+	require.NoError(t, k.Sign("alice", key))
+	_, err := TrustKeyring(pin, reparse(t, k))
+	require.NoError(t, err)
+
+	_, err = TrustKeyring(pin, older)
+
+	assert.ErrorIs(t, err, ErrKeyringRollback)
+}
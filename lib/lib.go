@@ -11,9 +11,13 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
 )
 
-var ErrNoEditorFound = errors.New("no editor found")
+var (
+	ErrNoEditorFound = errors.New("no editor found")
+	ErrNotATerminal  = errors.New("stdin is not a terminal")
+)
 
 func CreateTempFile(fileName string) (*os.File, error) {
 	date := time.Now().Format("01-02-2006_0204")
@@ -82,3 +86,19 @@ func AbsPath(fname string) string {
 	}
 	return fname
 }
+
+// ReadPassphrase prompts on stderr and reads a line from the terminal
+// without echoing it.
+func ReadPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("unable to prompt for passphrase: %w", ErrNotATerminal)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return pass, nil
+}
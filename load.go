@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"os"
 	"time"
-
-	"github.com/rs/zerolog/log"
 )
 
 type Variable struct {
@@ -16,43 +14,135 @@ type Variable struct {
 
 func (v Variable) Data() []byte {
 	res := append(v.Key, byte('=')) //nolint:gocritic
-	res = append(res, v.Value...)
+	res = append(res, quoteValue(v.Value)...)
 	res = append(res, byte('\n'))
 	return res
 }
 
+// Env returns the variable in the KEY=value form expected by os/exec.
+func (v Variable) Env() string {
+	return string(v.Key) + "=" + string(v.Value)
+}
+
 type EnvFile struct {
-	data      []byte
-	Variables []Variable
-	CreatedAt time.Time
+	data        []byte
+	Format      Format
+	ContentType string
+	Document    Document
+	Variables   []Variable
+	CreatedAt   time.Time
 }
 
 func (ef *EnvFile) Parse() error {
-	for i, line := range bytes.Split(ef.data, []byte("\n")) {
-		line = bytes.TrimSpace(line)
-		if line == nil {
-			continue
-		}
-		if line[0] == '#' {
-			log.Debug().Msgf("parsing comment: %s", line)
-			continue
+	if ef.Format == FormatRaw {
+		return nil
+	}
+	if ef.Format != "" && ef.Format != FormatDotenv {
+		vars, err := parseStructured(ef.Format, ef.data)
+		if err != nil {
+			return fmt.Errorf("error parsing %s file: %w", ef.Format, err)
 		}
-		split := bytes.Split(line, []byte("="))
-		if len(split) != 2 {
-			return fmt.Errorf("error parsing line: %d %w", i, ErrInvalidFormat)
+		ef.Variables = vars
+		return nil
+	}
+	doc, err := parseDocument(ef.data)
+	if err != nil {
+		return fmt.Errorf("error parsing env file: %w", err)
+	}
+	ef.Format = FormatDotenv
+	ef.Document = doc
+	ef.Variables = doc.Variables()
+	return nil
+}
+
+// Bytes returns the file content in its original format, with comments,
+// blank lines, ordering and quoting preserved.
+func (ef *EnvFile) Bytes() []byte {
+	if ef.Format != FormatDotenv {
+		return ef.data
+	}
+	return ef.Document.Bytes()
+}
+
+// Get returns the value of the key, the last one wins when it's repeated.
+func (ef *EnvFile) Get(key string) ([]byte, bool) {
+	var value []byte
+	found := false
+	for _, v := range ef.Variables {
+		if string(v.Key) == key {
+			value, found = v.Value, true
 		}
-		ef.Variables = append(ef.Variables, Variable{Key: split[0], Value: split[1]})
 	}
+	return value, found
+}
+
+// Set assigns the value to the key of a dotenv config, other formats can't
+// be modified by key.
+func (ef *EnvFile) Set(key string, value []byte) error {
+	if err := ef.editable(); err != nil {
+		return err
+	}
+	if err := ef.Document.Set(key, value); err != nil {
+		return err
+	}
+	ef.Variables = ef.Document.Variables()
 	return nil
 }
 
-func LoadEnvFile(fn string) (*EnvFile, error) {
-	data, err := os.ReadFile(fn)
+// Unset removes the key of a dotenv config, reporting whether it was set.
+func (ef *EnvFile) Unset(key string) (bool, error) {
+	if err := ef.editable(); err != nil {
+		return false, err
+	}
+	removed := ef.Document.Unset(key)
+	ef.Variables = ef.Document.Variables()
+	return removed, nil
+}
+
+func (ef *EnvFile) editable() error {
+	switch ef.Format {
+	case FormatDotenv:
+		return nil
+	case FormatRaw:
+		return ErrRawConfig
+	}
+	return fmt.Errorf("%s: %w", ef.Format, ErrNotDotenv)
+}
+
+// Payload returns the content to be encrypted. Files other than dotenv are
+// prefixed with a format marker so they can be parsed back after decryption.
+func (ef *EnvFile) Payload() []byte {
+	if ef.Format == FormatDotenv {
+		return ef.Bytes()
+	}
+	header := PayloadHeader{Format: ef.Format, ContentType: ef.ContentType}
+	return append([]byte(header.line()), ef.Bytes()...)
+}
+
+func ParseEnvFile(data []byte) (*EnvFile, error) {
+	return parseFile(data, FormatDotenv)
+}
+
+// ParsePayload parses decrypted content produced by EnvFile.Payload.
+func ParsePayload(data []byte) (*EnvFile, error) {
+	if !bytes.HasPrefix(data, []byte(payloadFormatPrefix)) {
+		return ParseEnvFile(data)
+	}
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	header, err := parsePayloadHeader(string(line))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %s %w", fn, err)
+		return nil, err
+	}
+	if header.IsRaw() {
+		return &EnvFile{data: rest, Format: FormatRaw, ContentType: header.ContentType, CreatedAt: time.Now().UTC()}, nil
 	}
+	return parseFile(rest, header.Format)
+}
+
+func parseFile(data []byte, format Format) (*EnvFile, error) {
 	envFile := &EnvFile{
 		data:      data,
+		Format:    format,
 		CreatedAt: time.Now().UTC(),
 	}
 	if err := envFile.Parse(); err != nil {
@@ -60,3 +150,20 @@ func LoadEnvFile(fn string) (*EnvFile, error) {
 	}
 	return envFile, nil
 }
+
+func LoadEnvFile(fn string) (*EnvFile, error) {
+	return LoadFile(fn, FormatDotenv)
+}
+
+// LoadFile reads a config file of the given format, detecting the format
+// from the file extension when it is empty.
+func LoadFile(fn string, format Format) (*EnvFile, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %s %w", fn, err)
+	}
+	if format == "" {
+		format = DetectFormat(fn)
+	}
+	return parseFile(data, format)
+}
@@ -1,6 +1,7 @@
 package dolores
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,3 +19,160 @@ func TestShouldLoadPlainEnvSuccessfully(t *testing.T) {
 	assert.Equal(t, Variable{[]byte("key4"), []byte("something")}, ef.Variables[3])
 	assert.NotEmpty(t, ef.CreatedAt)
 }
+
+func TestShouldParseFullDotenvGrammar(t *testing.T) {
+	ef, err := LoadEnvFile("./testdata/full.env")
+
+	require.NoError(t, err)
+	expected := []Variable{
+		{[]byte("DATABASE_URL"), []byte("postgres://u:p@h/db?sslmode=require")},
+		{[]byte("TOKEN"), []byte("c2VjcmV0dG9rZW4==")},
+		{[]byte("SINGLE"), []byte(`literal \n $HOME`)},
+		{[]byte("DOUBLE"), []byte("line one\nline \"two\" $HOME")},
+		{[]byte("SPACED"), []byte("value with spaces")},
+		{[]byte("HASH"), []byte("value#not-a-comment")},
+		{[]byte("EMPTY"), []byte{}},
+		{[]byte("PEM"), []byte("-----BEGIN KEY-----\nabc\n-----END KEY-----")},
+	}
+	assert.Equal(t, expected, ef.Variables)
+}
+
+func TestShouldReportPositionOfInvalidLine(t *testing.T) {
+	testCases := map[string]struct {
+		data   string
+		line   int
+		column int
+	}{
+		"missing equals":      {data: "key1=value1\nkey2 value2\n", line: 2, column: 6},
+		"unterminated quotes": {data: "key1=\"value1\nkey2=value2\n", line: 1, column: 6},
+		"invalid name":        {data: "1key=value\n", line: 1, column: 1},
+		"garbage after quote": {data: "key='value' more\n", line: 1, column: 13},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseEnvFile([]byte(tc.data))
+
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+			assert.Equal(t, tc.line, perr.Line)
+			assert.Equal(t, tc.column, perr.Column)
+		})
+	}
+}
+
+func TestShouldRoundTripVariablesThroughData(t *testing.T) {
+	ef, err := LoadEnvFile("./testdata/full.env")
+	require.NoError(t, err)
+	var data []byte
+	for _, v := range ef.Variables {
+		data = append(data, v.Data()...)
+	}
+
+	result, err := ParseEnvFile(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, ef.Variables, result.Variables)
+	assert.Contains(t, string(data), "DATABASE_URL=postgres://u:p@h/db?sslmode=require\n")
+	assert.Contains(t, string(data), `SPACED="value with spaces"`)
+}
+
+func TestShouldPreserveDocumentFormatting(t *testing.T) {
+	data, err := os.ReadFile("./testdata/full.env")
+	require.NoError(t, err)
+
+	ef, err := ParseEnvFile(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(ef.Bytes()))
+	kinds := make([]NodeKind, 0)
+	for _, n := range ef.Document.Nodes[:5] {
+		kinds = append(kinds, n.Kind)
+	}
+	assert.Equal(t, []NodeKind{CommentNode, VariableNode, VariableNode, BlankNode, VariableNode}, kinds)
+	assert.Equal(t, byte('\''), ef.Document.Nodes[4].Quote)
+}
+
+func TestShouldSetAndUnsetKeysKeepingLayout(t *testing.T) {
+	ef, err := ParseEnvFile([]byte("# db\nHOST='localhost'\nPORT=5432"))
+	require.NoError(t, err)
+
+	require.NoError(t, ef.Set("HOST", []byte("db.internal")))
+	require.NoError(t, ef.Set("TOKEN", []byte("a b")))
+	removed, err := ef.Unset("PORT")
+	require.NoError(t, err)
+
+	assert.True(t, removed)
+	assert.Equal(t, "# db\nHOST='db.internal'\nTOKEN=\"a b\"\n", string(ef.Bytes()))
+	value, ok := ef.Get("TOKEN")
+	assert.True(t, ok)
+	assert.Equal(t, "a b", string(value))
+	assert.ErrorIs(t, ef.Set("1KEY", nil), ErrInvalidKey)
+
+	structured, err := parseFile([]byte(`{"db": {"host": "x"}}`), FormatJSON)
+	require.NoError(t, err)
+	assert.ErrorIs(t, structured.Set("DB__HOST", []byte("y")), ErrNotDotenv)
+}
+
+func TestShouldSetKeyKeepingExportAndComment(t *testing.T) {
+	ef, err := ParseEnvFile([]byte("export K=v # note\nexport Q = \"x\"  # quoted\nLAST=1"))
+	require.NoError(t, err)
+
+	require.NoError(t, ef.Set("K", []byte("new value")))
+	require.NoError(t, ef.Set("Q", []byte("y")))
+	require.NoError(t, ef.Set("LAST", []byte("2")))
+
+	assert.Equal(t, "export K=\"new value\" # note\nexport Q = \"y\"  # quoted\nLAST=2", string(ef.Bytes()))
+	value, ok := ef.Get("K")
+	assert.True(t, ok)
+	assert.Equal(t, "new value", string(value))
+}
+
+func TestShouldFlattenStructuredFormats(t *testing.T) {
+	expected := []Variable{
+		{[]byte("API_KEY"), []byte("s3cr3t")},
+		{[]byte("DB__HOST"), []byte("localhost")},
+		{[]byte("DB__PORT"), []byte("5432")},
+		{[]byte("DB__SSL"), []byte("true")},
+		{[]byte("SERVERS__0"), []byte("a")},
+		{[]byte("SERVERS__1"), []byte("b")},
+	}
+	for _, fname := range []string{"app.json", "app.yaml", "app.toml"} {
+		t.Run(fname, func(t *testing.T) {
+			ef, err := LoadFile("./testdata/"+fname, "")
+
+			require.NoError(t, err)
+			assert.Equal(t, DetectFormat(fname), ef.Format)
+			assert.Equal(t, expected, ef.Variables)
+		})
+	}
+}
+
+func TestShouldParseJavaProperties(t *testing.T) {
+	ef, err := LoadFile("./testdata/app.properties", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, FormatProperties, ef.Format)
+	assert.Equal(t, []Variable{
+		{[]byte("DB__HOST"), []byte("localhost")},
+		{[]byte("DB__PORT"), []byte("5432")},
+		{[]byte("DB__SSL"), []byte("true")},
+		{[]byte("SERVERS__0"), []byte("a")},
+		{[]byte("SERVERS__1"), []byte("b")},
+		{[]byte("API_KEY"), []byte("s3cr3t")},
+	}, ef.Variables)
+}
+
+func TestShouldKeepOriginalStructureInPayload(t *testing.T) {
+	data, err := os.ReadFile("./testdata/app.yaml")
+	require.NoError(t, err)
+	ef, err := LoadFile("./testdata/app.yaml", "")
+	require.NoError(t, err)
+
+	result, err := ParsePayload(ef.Payload())
+
+	require.NoError(t, err)
+	assert.Equal(t, FormatYAML, result.Format)
+	assert.Equal(t, string(data), string(result.Bytes()))
+	assert.Equal(t, ef.Variables, result.Variables)
+}
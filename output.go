@@ -0,0 +1,210 @@
+package dolores
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type OutputFormat string
+
+const (
+	OutputDotenv     OutputFormat = "dotenv"
+	OutputJSON       OutputFormat = "json"
+	OutputYAML       OutputFormat = "yaml"
+	OutputExport     OutputFormat = "export"
+	OutputDocker     OutputFormat = "docker"
+	OutputKubernetes OutputFormat = "kubernetes"
+)
+
+var (
+	ErrInvalidOutputFormat = errors.New("invalid output format")
+	ErrUnsupportedValue    = errors.New("value not supported by output format")
+)
+
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch f := OutputFormat(strings.ToLower(name)); f {
+	case OutputDotenv, OutputJSON, OutputYAML, OutputExport, OutputDocker, OutputKubernetes:
+		return f, nil
+	case "env":
+		return OutputDotenv, nil
+	case "k8s":
+		return OutputKubernetes, nil
+	}
+	return "", fmt.Errorf("%s: %w", name, ErrInvalidOutputFormat)
+}
+
+type RenderConfig struct {
+	Format OutputFormat
+	// Name, Namespace and Labels are used for the kubernetes Secret manifest.
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// Render writes the variables of the config in the output format, raw
+// configs have no variables and can only be written as is.
+func (ef *EnvFile) Render(w io.Writer, cfg RenderConfig) error {
+	if ef.Format == FormatRaw {
+		return ErrRawConfig
+	}
+	return Render(w, ef.Variables, cfg)
+}
+
+// revive:disable cyclomatic
+func Render(w io.Writer, vars []Variable, cfg RenderConfig) error {
+	var data []byte
+	var err error
+	switch cfg.Format {
+	case OutputDotenv:
+		data = renderDotenv(vars)
+	case OutputJSON:
+		data, err = renderJSON(vars)
+	case OutputYAML:
+		data, err = renderYAML(vars)
+	case OutputExport:
+		data, err = renderExport(vars)
+	case OutputDocker:
+		data, err = renderDocker(vars)
+	case OutputKubernetes:
+		data, err = renderKubernetes(vars, cfg)
+	default:
+		return fmt.Errorf("%s: %w", cfg.Format, ErrInvalidOutputFormat)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+func renderDotenv(vars []Variable) []byte {
+	buf := &bytes.Buffer{}
+	for _, v := range vars {
+		buf.Write(v.Data())
+	}
+	return buf.Bytes()
+}
+
+func renderJSON(vars []Variable) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+	for i, v := range vars {
+		key, err := json.Marshal(string(v.Key))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(string(v.Value))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(buf, "\n  %s: %s", key, value)
+	}
+	buf.WriteString("\n}\n")
+	return buf.Bytes(), nil
+}
+
+func renderYAML(vars []Variable) ([]byte, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range vars {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v.Key)},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v.Value)},
+		)
+	}
+	return encodeYAML(node)
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderExport writes shell-sourcable assignments, single quoting every value.
+func renderExport(vars []Variable) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, v := range vars {
+		if !isShellName(v.Key) {
+			return nil, fmt.Errorf("%s is not a valid shell variable name: %w", v.Key, ErrUnsupportedValue)
+		}
+		value := strings.ReplaceAll(string(v.Value), "'", `'\''`)
+		fmt.Fprintf(buf, "export %s='%s'\n", v.Key, value)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderDocker writes the docker --env-file format, which takes values
+// literally and cannot hold multiline values.
+func renderDocker(vars []Variable) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, v := range vars {
+		if bytes.ContainsAny(v.Value, "\r\n") {
+			return nil, fmt.Errorf("multiline value for %s: %w", v.Key, ErrUnsupportedValue)
+		}
+		buf.WriteString(v.Env())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+type k8sMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+func renderKubernetes(vars []Variable, cfg RenderConfig) ([]byte, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("kubernetes secret name is required: %w", ErrInvalidConfiguraion)
+	}
+	sec := k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: cfg.Name, Namespace: cfg.Namespace, Labels: cfg.Labels},
+		Type:       "Opaque",
+		Data:       make(map[string]string, len(vars)),
+	}
+	for _, v := range vars {
+		sec.Data[string(v.Key)] = base64.StdEncoding.EncodeToString(v.Value)
+	}
+	return encodeYAML(sec)
+}
+
+func isShellName(key []byte) bool {
+	if len(key) == 0 || !isKeyStart(key[0]) {
+		return false
+	}
+	for _, c := range key {
+		if !isKeyStart(c) && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
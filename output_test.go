@@ -0,0 +1,73 @@
+package dolores
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldRenderOutputFormats(t *testing.T) {
+	vars := []Variable{
+		{[]byte("DB_URL"), []byte("postgres://u:p@h/db?sslmode=require")},
+		{[]byte("GREETING"), []byte("it's on")},
+	}
+	testCases := map[OutputFormat]string{
+		OutputDotenv: "DB_URL=postgres://u:p@h/db?sslmode=require\nGREETING=\"it's on\"\n",
+		OutputJSON:   "{\n  \"DB_URL\": \"postgres://u:p@h/db?sslmode=require\",\n  \"GREETING\": \"it's on\"\n}\n",
+		OutputYAML:   "DB_URL: postgres://u:p@h/db?sslmode=require\nGREETING: it's on\n",
+		OutputExport: "export DB_URL='postgres://u:p@h/db?sslmode=require'\nexport GREETING='it'\\''s on'\n",
+		OutputDocker: "DB_URL=postgres://u:p@h/db?sslmode=require\nGREETING=it's on\n",
+	}
+	for format, expected := range testCases {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			err := Render(buf, vars, RenderConfig{Format: format})
+
+			require.NoError(t, err)
+			assert.Equal(t, expected, buf.String())
+		})
+	}
+}
+
+func TestShouldRenderKubernetesSecret(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := RenderConfig{Format: OutputKubernetes, Name: "backend", Namespace: "apps", Labels: map[string]string{"team": "core"}}
+
+	err := Render(buf, []Variable{{[]byte("KEY"), []byte("value")}}, cfg)
+
+	require.NoError(t, err)
+	expected := `apiVersion: v1
+kind: Secret
+metadata:
+  name: backend
+  namespace: apps
+  labels:
+    team: core
+type: Opaque
+data:
+  KEY: dmFsdWU=
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestShouldRejectMultilineValuesForDocker(t *testing.T) {
+	err := Render(&bytes.Buffer{}, []Variable{{[]byte("PEM"), []byte("a\nb")}}, RenderConfig{Format: OutputDocker})
+
+	assert.ErrorIs(t, err, ErrUnsupportedValue)
+}
+
+func TestShouldRefuseToRenderRawConfig(t *testing.T) {
+	ef, err := ParsePayload([]byte("#dolores:format=raw content-type=application/json\n{}"))
+	require.NoError(t, err)
+	for _, format := range []OutputFormat{OutputDotenv, OutputJSON, OutputYAML, OutputExport, OutputDocker, OutputKubernetes} {
+		buf := &bytes.Buffer{}
+
+		err := ef.Render(buf, RenderConfig{Format: format, Name: "sa"})
+
+		require.ErrorIs(t, err, ErrRawConfig, format)
+		assert.Empty(t, buf.String())
+	}
+}
@@ -2,36 +2,228 @@ package dolores
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"strings"
+	"time"
 
 	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/scalescape/dolores/lib"
+	"golang.org/x/crypto/ssh"
 )
 
+// PassphraseFunc returns the passphrase of a protected key file, prompting
+// with the given message when it has to ask the user.
+type PassphraseFunc func(prompt string) ([]byte, error)
+
+// PassphraseEnv unlocks protected identity files without a terminal prompt.
+const PassphraseEnv = "DOLORES_PASSPHRASE"
+
 func ParseIdentities(keyFile string) ([]age.Identity, error) {
+	return parseIdentities(keyFile, lib.ReadPassphrase)
+}
+
+func parseIdentities(keyFile string, passphrase PassphraseFunc) ([]age.Identity, error) {
 	// process identity from keyfile
-	f, err := os.Open(keyFile)
+	data, err := os.ReadFile(keyFile)
 	if err != nil {
 		return nil, fmt.Errorf("error opening keyfile %s: %w", keyFile, err)
 	}
-	defer f.Close()
-	ids, err := age.ParseIdentities(f)
+	if isProtectedKey(data) {
+		if data, err = unlockKey(keyFile, data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	if isSSHPrivateKey(data) {
+		id, err := parseSSHIdentity(keyFile, data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh identity: %w", err)
+		}
+		return []age.Identity{id}, nil
+	}
+	ids, err := age.ParseIdentities(bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse identity: %w", err)
 	}
 	return ids, nil
 }
 
-// revive:disable function-length
+// ParseRecipient accepts age X25519 public keys as well as ssh-ed25519 and
+// ssh-rsa public keys in authorized_keys format.
+func ParseRecipient(key string) (age.Recipient, error) { //nolint:ireturn
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "ssh-") {
+		r, err := agessh.ParseRecipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssh recipient: %w", err)
+		}
+		return r, nil
+	}
+	r, err := age.ParseX25519Recipient(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return r, nil
+}
+
+// GenerateIdentity creates an age identity and returns the content of its
+// key file, protected when a passphrase is given, with its public key.
+func GenerateIdentity(passphrase []byte) ([]byte, string, error) {
+	k, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	pubKey := k.Recipient().String()
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# created: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(buf, "# public key: %s\n", pubKey)
+	fmt.Fprintf(buf, "%s\n", k)
+	data := buf.Bytes()
+	if passphrase != nil {
+		if data, err = ProtectIdentity(data, passphrase); err != nil {
+			return nil, "", err
+		}
+	}
+	return data, pubKey, nil
+}
+
+// ProtectIdentity encrypts the identity file content with a scrypt
+// passphrase, so it's only usable after unlocking.
+func ProtectIdentity(data, passphrase []byte) ([]byte, error) {
+	recp, err := age.NewScryptRecipient(string(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	enc := &Encryptor{recipients: []age.Recipient{recp}}
+	if err := enc.encryptArmored(buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func isProtectedKey(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) ||
+		bytes.HasPrefix(data, []byte("age-encryption.org/"))
+}
+
+func unlockKey(keyFile string, data []byte, passphrase PassphraseFunc) ([]byte, error) {
+	pass := []byte(os.Getenv(PassphraseEnv))
+	if len(pass) == 0 {
+		var err error
+		if pass, err = passphrase(fmt.Sprintf("Enter passphrase for %s: ", keyFile)); err != nil {
+			return nil, err
+		}
+	}
+	id, err := age.NewScryptIdentity(string(pass))
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+	rdr, err := age.Decrypt(src, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock keyfile %s: %w", keyFile, err)
+	}
+	result, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile %s: %w", keyFile, err)
+	}
+	return result, nil
+}
+
+// RememberPassphrase asks once and reuses the answer, for long running
+// processes that decrypt repeatedly.
+func RememberPassphrase(fn PassphraseFunc) PassphraseFunc {
+	var pass []byte
+	return func(prompt string) ([]byte, error) {
+		if pass != nil {
+			return pass, nil
+		}
+		p, err := fn(prompt)
+		if err != nil {
+			return nil, err
+		}
+		pass = p
+		return pass, nil
+	}
+}
+
+func isSSHPrivateKey(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) &&
+		bytes.Contains(data, []byte("PRIVATE KEY-----"))
+}
+
+// parseSSHIdentity decrypts protected keys lazily, so the passphrase is only
+// asked for when a file is actually encrypted to the key.
+func parseSSHIdentity(keyFile string, data []byte, passphrase PassphraseFunc) (age.Identity, error) { //nolint:ireturn
+	id, err := agessh.ParseIdentity(data)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return id, err //nolint:wrapcheck
+	}
+	pub := missing.PublicKey
+	if pub == nil {
+		if pub, err = readSSHPublicKey(keyFile + ".pub"); err != nil {
+			return nil, err
+		}
+	}
+	prompt := fmt.Sprintf("Enter passphrase for %s: ", keyFile)
+	return agessh.NewEncryptedSSHIdentity(pub, data, func() ([]byte, error) { //nolint:wrapcheck
+		return passphrase(prompt)
+	})
+}
+
+func readSSHPublicKey(fname string) (ssh.PublicKey, error) { //nolint:ireturn
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("protected ssh key requires public key %s: %w", fname, err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh public key %s: %w", fname, err)
+	}
+	return pub, nil
+}
+
+// ReadPublicKey returns the recipient of the key file. For ssh keys it's read
+// from the .pub file next to it, falling back to the unprotected private key.
+// Protected key files are unlocked first.
 func ReadPublicKey(fname string) (string, error) {
-	keyFile, err := os.Open(fname)
+	data, err := os.ReadFile(fname)
 	if err != nil {
 		return "", fmt.Errorf("error opening keyfile %s: %w", fname, err)
 	}
+	if isProtectedKey(data) {
+		if data, err = unlockKey(fname, data, lib.ReadPassphrase); err != nil {
+			return "", err
+		}
+	}
+	if !isSSHPrivateKey(data) {
+		return readAgePublicKey(data)
+	}
+	if pub, err := readSSHPublicKey(fname + ".pub"); err == nil {
+		return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))), nil
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to extract public key: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
+
+// revive:disable function-length
+func readAgePublicKey(data []byte) (string, error) {
 	const recipientFileSizeLimit = 1 << 24 // 16 MiB
-	scanner := bufio.NewScanner(io.LimitReader(keyFile, recipientFileSizeLimit))
+	scanner := bufio.NewScanner(io.LimitReader(bytes.NewReader(data), recipientFileSizeLimit))
 	var n int
 	re := regexp.MustCompile(`^#\s+public key.*(age1.*)`)
 	for scanner.Scan() {
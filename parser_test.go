@@ -0,0 +1,146 @@
+package dolores
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func writeSSHKey(t *testing.T, passphrase string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(priv, "alice@example")
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "alice@example", []byte(passphrase))
+	}
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600))
+	return keyFile, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+func TestShouldEncryptToSSHRecipientAndDecryptWithSSHKeyFile(t *testing.T) {
+	keyFile, pubKey := writeSSHKey(t, "")
+	enc, err := NewEncryptor(alicePubKey, pubKey+" alice@example\n")
+	require.NoError(t, err)
+	data, err := enc.Encrypt([]Variable{{[]byte("KEY"), []byte("value")}})
+	require.NoError(t, err)
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: keyFile})
+	require.NoError(t, err)
+
+	result, err := dec.Decrypt(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", string(result))
+}
+
+func TestShouldPromptForPassphraseOfProtectedSSHKey(t *testing.T) {
+	keyFile, pubKey := writeSSHKey(t, "secret")
+	enc, err := NewEncryptor(pubKey)
+	require.NoError(t, err)
+	data, err := enc.Encrypt([]Variable{{[]byte("KEY"), []byte("value")}})
+	require.NoError(t, err)
+	var prompts []string
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: keyFile}, WithPassphrase(func(prompt string) ([]byte, error) {
+		prompts = append(prompts, prompt)
+		return []byte("secret"), nil
+	}))
+	require.NoError(t, err)
+
+	result, err := dec.Decrypt(data)
+
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", string(result))
+	assert.Equal(t, []string{"Enter passphrase for " + keyFile + ": "}, prompts)
+}
+
+func TestShouldReadPublicKeyOfSSHKeyFile(t *testing.T) {
+	keyFile, pubKey := writeSSHKey(t, "")
+
+	result, err := ReadPublicKey(keyFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, result)
+	ageKey, err := ReadPublicKey(aliceKeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, alicePubKey, ageKey)
+}
+
+func TestShouldRejectInvalidRecipient(t *testing.T) {
+	_, err := NewEncryptor("ssh-ed25519 invalid")
+
+	assert.ErrorContains(t, err, "invalid ssh recipient")
+}
+
+func writeProtectedKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	data, err := os.ReadFile(aliceKeyFile)
+	require.NoError(t, err)
+	protected, err := ProtectIdentity(data, []byte(passphrase))
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "staging.key")
+	require.NoError(t, os.WriteFile(keyFile, protected, 0o600))
+	return keyFile
+}
+
+func TestShouldDecryptWithProtectedIdentityFile(t *testing.T) {
+	keyFile := writeProtectedKey(t, "secret")
+	data, err := os.ReadFile("./testdata/sample.age")
+	require.NoError(t, err)
+	t.Setenv(PassphraseEnv, "")
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: keyFile}, WithPassphrase(func(string) ([]byte, error) {
+		return []byte("secret"), nil
+	}))
+	require.NoError(t, err)
+
+	result, err := dec.Decrypt(data)
+
+	require.NoError(t, err)
+	expected, err := os.ReadFile("./testdata/sample.env")
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestShouldUnlockProtectedIdentityFromEnv(t *testing.T) {
+	keyFile := writeProtectedKey(t, "secret")
+	t.Setenv(PassphraseEnv, "secret")
+
+	pubKey, err := ReadPublicKey(keyFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, alicePubKey, pubKey)
+}
+
+func TestShouldFailToUnlockProtectedIdentityWithWrongPassphrase(t *testing.T) {
+	keyFile := writeProtectedKey(t, "secret")
+	t.Setenv(PassphraseEnv, "wrong")
+
+	_, err := ParseIdentities(keyFile)
+
+	assert.ErrorContains(t, err, "failed to unlock keyfile")
+}
+
+func TestShouldGenerateProtectedIdentity(t *testing.T) {
+	data, pubKey, err := GenerateIdentity([]byte("secret"))
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "staging.key")
+	require.NoError(t, os.WriteFile(keyFile, data, 0o600))
+	t.Setenv(PassphraseEnv, "secret")
+
+	readKey, err := ReadPublicKey(keyFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, readKey)
+	assert.True(t, strings.HasPrefix(pubKey, "age1"))
+}
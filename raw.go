@@ -0,0 +1,101 @@
+package dolores
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// FormatRaw marks opaque files like TLS keys or service account JSONs,
+// they're stored as is and never parsed into variables.
+const FormatRaw Format = "raw"
+
+var ErrRawConfig = errors.New("config is a raw file")
+
+// PayloadHeader describes the decrypted content, read from the marker line
+// written by EnvFile.Payload and RawPayload.
+type PayloadHeader struct {
+	Format      Format
+	ContentType string
+}
+
+func (h PayloadHeader) IsRaw() bool {
+	return h.Format == FormatRaw
+}
+
+func (h PayloadHeader) line() string {
+	line := payloadFormatPrefix + string(h.Format)
+	if h.ContentType != "" {
+		line += " content-type=" + h.ContentType
+	}
+	return line + "\n"
+}
+
+func parsePayloadHeader(line string) (PayloadHeader, error) {
+	fields := strings.Fields(strings.TrimPrefix(line, payloadFormatPrefix))
+	if len(fields) == 0 {
+		return PayloadHeader{}, fmt.Errorf("empty payload header: %w", ErrInvalidFormat)
+	}
+	header := PayloadHeader{Format: FormatRaw}
+	if fields[0] != string(FormatRaw) {
+		format, err := ParseFormat(fields[0])
+		if err != nil {
+			return PayloadHeader{}, err
+		}
+		header.Format = format
+	}
+	for _, field := range fields[1:] {
+		if ct, ok := strings.CutPrefix(field, "content-type="); ok {
+			header.ContentType = ct
+		}
+	}
+	return header, nil
+}
+
+// ReadPayloadHeader reads the marker line of decrypted content and returns a
+// reader of what follows it. Content without a marker is a dotenv file.
+func ReadPayloadHeader(r io.Reader) (PayloadHeader, io.Reader, error) {
+	br := bufio.NewReader(r)
+	prefix, err := br.Peek(len(payloadFormatPrefix))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return PayloadHeader{}, nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if !bytes.Equal(prefix, []byte(payloadFormatPrefix)) {
+		return PayloadHeader{Format: FormatDotenv}, br, nil
+	}
+	line, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return PayloadHeader{}, nil, fmt.Errorf("failed to read payload header: %w", err)
+	}
+	header, err := parsePayloadHeader(line)
+	if err != nil {
+		return PayloadHeader{}, nil, err
+	}
+	return header, br, nil
+}
+
+// RawPayload prefixes the file content with a raw marker, detecting the
+// content type from the file name or the first bytes when it's empty.
+func RawPayload(fname, contentType string, r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if contentType == "" {
+		contentType = DetectContentType(fname, br)
+	}
+	header := PayloadHeader{Format: FormatRaw, ContentType: contentType}
+	return io.MultiReader(strings.NewReader(header.line()), br)
+}
+
+func DetectContentType(fname string, br *bufio.Reader) string {
+	if ct := mime.TypeByExtension(filepath.Ext(fname)); ct != "" {
+		return strings.ReplaceAll(ct, " ", "")
+	}
+	const sniffLen = 512
+	head, _ := br.Peek(sniffLen)
+	return strings.ReplaceAll(http.DetectContentType(head), " ", "")
+}
@@ -0,0 +1,236 @@
+package dolores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+)
+
+var (
+	ErrSignatureRequired = errors.New("refusing unverified config, load it WithAllowUnsigned to read it anyway")
+	ErrNoSigners         = errors.New("fetcher can't list the signers, verify with WithSigners")
+)
+
+type SecretFetcher interface {
+	FetchSecrets(req client.FetchSecretRequest) ([]byte, error)
+}
+
+type SecretLister interface {
+	SecretFetcher
+	GetSecretList(cfg client.SecretListConfig) ([]client.SecretObject, error)
+}
+
+// signerSource is implemented by the backends that publish the signing keys
+// of the team, configs are verified against them by default.
+type signerSource interface {
+	GetKeyring(env string) ([]byte, error)
+	GetSigners(env string) ([]client.Signer, error)
+}
+
+// Values holds the decrypted variables of a config keyed by name.
+type Values map[string]string
+
+func (v Values) Setenv() error {
+	for key, value := range v {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func valuesOf(vars []Variable) Values {
+	values := make(Values, len(vars))
+	for _, v := range vars {
+		values[string(v.Key)] = string(v.Value)
+	}
+	return values
+}
+
+type LoadConfig struct {
+	DecryptConfig
+	Setenv        bool
+	AllowUnsigned bool
+	Fetcher       SecretFetcher
+}
+
+type LoadOpt func(cfg *LoadConfig)
+
+func WithDecryptOpts(opts ...DecryptOpt) LoadOpt {
+	return func(c *LoadConfig) {
+		for _, opt := range opts {
+			opt(&c.DecryptConfig)
+		}
+	}
+}
+
+// WithSetenv exports the loaded variables into the process environment.
+func WithSetenv() LoadOpt {
+	return func(c *LoadConfig) {
+		c.Setenv = true
+	}
+}
+
+// WithAllowUnsigned reads configs that aren't signed by a known team member,
+// the same as --allow-unsigned.
+func WithAllowUnsigned() LoadOpt {
+	return func(c *LoadConfig) {
+		c.AllowUnsigned = true
+	}
+}
+
+// WithFetcher overrides the backend used to fetch the encrypted config.
+func WithFetcher(f SecretFetcher) LoadOpt {
+	return func(c *LoadConfig) {
+		c.Fetcher = f
+	}
+}
+
+// Load fetches the named config of the environment, decrypts it in-process
+// and returns its variables.
+func Load(ctx context.Context, env, name string, opts ...LoadOpt) (Values, error) {
+	cfg := newLoadConfig(env, opts...)
+	ef, err := fetch(ctx, env, name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	values := valuesOf(ef.Variables)
+	if cfg.Setenv {
+		if err := values.Setenv(); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Fetch is like Load but returns the parsed config, keeping the order and
+// format of the uploaded file.
+func Fetch(ctx context.Context, env, name string, opts ...LoadOpt) (*EnvFile, error) {
+	return fetch(ctx, env, name, newLoadConfig(env, opts...))
+}
+
+func fetch(ctx context.Context, env, name string, cfg *LoadConfig) (*EnvFile, error) {
+	if cfg.Fetcher == nil {
+		f, err := newBackend(ctx, env)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Fetcher = f
+	}
+	if err := cfg.verify(env); err != nil {
+		return nil, err
+	}
+	dec, err := NewDecryptor(&cfg.DecryptConfig)
+	if err != nil {
+		return nil, err
+	}
+	return fetchFile(cfg.Fetcher, dec, env, name)
+}
+
+// verify refuses configs that aren't signed by a known team member, unless
+// they're explicitly allowed. Without WithSigners the envelope is checked
+// against the keyring of the environment, or the signing keys published to
+// the server.
+func (c *LoadConfig) verify(env string) error {
+	if c.VerifyEnvelope == nil {
+		signers, err := fetchSigners(c.Fetcher, env)
+		if err != nil && !c.AllowUnsigned {
+			return err
+		}
+		WithSigners(signers...)(&c.DecryptConfig)
+	}
+	verify, allow := c.VerifyEnvelope, c.AllowUnsigned
+	c.VerifyEnvelope = func(e *Envelope) error {
+		err := verify(e)
+		if err == nil || allow {
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrSignatureRequired, err)
+	}
+	return nil
+}
+
+func fetchSigners(f SecretFetcher, env string) ([]Signer, error) {
+	src, ok := f.(signerSource)
+	if !ok {
+		return nil, ErrNoSigners
+	}
+	data, err := src.GetKeyring(env)
+	if errors.Is(err, client.ErrMethodUndefined) {
+		published, err := src.GetSigners(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signing keys: %w", err)
+		}
+		signers := make([]Signer, len(published))
+		for i, s := range published {
+			signers[i] = Signer{ID: s.UserID, PublicKey: s.PublicKey}
+		}
+		return signers, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get keyring: %w", err)
+	}
+	k, err := ParseKeyring(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := TrustKeyring(config.KeyringPin(env), k); err != nil {
+		return nil, fmt.Errorf("failed to trust keyring: %w", err)
+	}
+	return k.Signers(), nil
+}
+
+func fetchFile(f SecretFetcher, dec Decryptor, env, name string) (*EnvFile, error) {
+	data, err := f.FetchSecrets(client.FetchSecretRequest{Environment: env, Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config %s: %w", name, err)
+	}
+	result, err := dec.Decrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	ef, err := ParsePayload(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", name, err)
+	}
+	return ef, nil
+}
+
+// newLoadConfig applies the options and falls back to the same key discovery
+// as the cli: the key file configured by init, then DOLORES_SECRETS_KEY_FILE
+// and DOLORES_SECRETS_KEY.
+func newLoadConfig(env string, opts ...LoadOpt) *LoadConfig {
+	cfg := new(LoadConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.Key != "" || cfg.KeyFile != "" {
+		return cfg
+	}
+	if d, err := config.LoadFromDisk(); err == nil {
+		cfg.KeyFile = d.Environments[env].KeyFile
+	}
+	if cfg.KeyFile == "" {
+		cfg.KeyFile = os.Getenv("DOLORES_SECRETS_KEY_FILE")
+	}
+	cfg.Key = os.Getenv("DOLORES_SECRETS_KEY")
+	return cfg
+}
+
+func newBackend(ctx context.Context, env string) (SecretLister, error) { //nolint:ireturn
+	if os.Getenv("MONART_API_TOKEN") != "" {
+		mcfg, err := config.LoadMonartClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.NewMonart(ctx, mcfg), nil
+	}
+	ccfg, err := config.LoadClient(ctx, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client config: %w", err)
+	}
+	return client.New(ctx, ccfg)
+}
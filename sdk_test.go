@@ -0,0 +1,90 @@
+package dolores
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/scalescape/dolores/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubFetcher struct {
+	data []byte
+	req  client.FetchSecretRequest
+}
+
+func (s *stubFetcher) FetchSecrets(req client.FetchSecretRequest) ([]byte, error) {
+	s.req = req
+	return s.data, nil
+}
+
+// signedFetcher publishes signing keys the way the server does, without a
+// keyring.
+type signedFetcher struct {
+	SecretFetcher
+	signers []client.Signer
+}
+
+func (s signedFetcher) GetKeyring(_ string) ([]byte, error) {
+	return nil, client.ErrMethodUndefined
+}
+
+func (s signedFetcher) GetSigners(_ string) ([]client.Signer, error) {
+	return s.signers, nil
+}
+
+func TestShouldLoadOnlyVerifiedConfigs(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	other, err := GenerateSigningKey()
+	require.NoError(t, err)
+	unsigned, err := os.ReadFile("./testdata/sample.age")
+	require.NoError(t, err)
+	signed := encryptSigned(t, key)
+	alice := []client.Signer{{UserID: "alice", PublicKey: key.Public()}}
+	stranger := []client.Signer{{UserID: "alice", PublicKey: other.Public()}}
+	tests := map[string]struct {
+		fetcher SecretFetcher
+		opts    []LoadOpt
+		err     error
+	}{
+		"known signer":     {fetcher: signedFetcher{&stubFetcher{data: signed}, alice}},
+		"unsigned":         {fetcher: signedFetcher{&stubFetcher{data: unsigned}, alice}, err: ErrUnsigned},
+		"unknown signer":   {fetcher: signedFetcher{&stubFetcher{data: signed}, stranger}, err: ErrUnknownSigner},
+		"no signers":       {fetcher: &stubFetcher{data: signed}, err: ErrNoSigners},
+		"unsigned allowed": {fetcher: signedFetcher{&stubFetcher{data: unsigned}, alice}, opts: []LoadOpt{WithAllowUnsigned()}},
+		"without signers":  {fetcher: &stubFetcher{data: signed}, opts: []LoadOpt{WithAllowUnsigned()}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := append([]LoadOpt{WithFetcher(tc.fetcher), WithDecryptOpts(WithKeyFile(aliceKeyFile))}, tc.opts...)
+
+			values, err := Load(context.Background(), "staging", "backend", opts...)
+
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Nil(t, values)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, values)
+		})
+	}
+}
+
+func TestShouldLoadRemoteConfigIntoProcess(t *testing.T) {
+	data, err := os.ReadFile("./testdata/sample.age")
+	require.NoError(t, err)
+	fetcher := &stubFetcher{data: data}
+	t.Setenv("key1", "")
+
+	values, err := Load(context.Background(), "staging", "backend", WithFetcher(fetcher),
+		WithDecryptOpts(WithKeyFile(aliceKeyFile)), WithSetenv(), WithAllowUnsigned())
+
+	require.NoError(t, err)
+	assert.Equal(t, client.FetchSecretRequest{Environment: "staging", Name: "backend"}, fetcher.req)
+	assert.Equal(t, Values{"key1": "value1", "key2": "valuetwo", "key3": "random", "key4": "something"}, values)
+	assert.Equal(t, "value1", os.Getenv("key1"))
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+)
+
+type DeleteSecretConfig struct {
+	Environment string
+	Name        string
+}
+
+func (c DeleteSecretConfig) Valid() error {
+	if err := config.ValidEnvironment(c.Environment); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
+	}
+	if c.Name == "" {
+		return ErrInvalidConfigName
+	}
+	return nil
+}
+
+func (sm SecretManager) DeleteSecret(cfg DeleteSecretConfig) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	req := client.DeleteSecretRequest{Environment: cfg.Environment, Name: cfg.Name}
+	if err := sm.client.DeleteSecret(req); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", cfg.Name, err)
+	}
+	sm.log.Info().Msgf("deleted config %s", cfg.Name)
+	return nil
+}
+
+type RenameSecretConfig struct {
+	Environment string
+	From        string
+	To          string
+}
+
+func (c RenameSecretConfig) Valid() error {
+	if err := config.ValidEnvironment(c.Environment); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
+	}
+	if c.From == "" || c.To == "" || c.From == c.To {
+		return ErrInvalidConfigName
+	}
+	return nil
+}
+
+// RenameSecret moves the config to the new name, the envelope doesn't
+// record the name so the config stays signed.
+func (sm SecretManager) RenameSecret(cfg RenameSecretConfig) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	req := client.RenameSecretRequest{Environment: cfg.Environment, From: cfg.From, To: cfg.To}
+	if err := sm.client.RenameSecret(req); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", cfg.From, err)
+	}
+	sm.log.Info().Msgf("renamed config %s to %s", cfg.From, cfg.To)
+	return nil
+}
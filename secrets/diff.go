@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+)
+
+const maskedValue = "********"
+
+type DiffConfig struct {
+	From, To DecryptConfig
+	// ShowValues prints the values of the keys that differ, they're
+	// masked otherwise.
+	ShowValues bool
+	JSON       bool
+	Out        io.Writer
+}
+
+func (c DiffConfig) output() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+// ConfigDiff lists the keys that differ between two versions of a config,
+// with their values when they're shown.
+type ConfigDiff struct {
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Added   []KeyChange `json:"added"`
+	Removed []KeyChange `json:"removed"`
+	Changed []KeyChange `json:"changed"`
+}
+
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+type KeyChange struct {
+	Key  string  `json:"key"`
+	From *string `json:"from,omitempty"`
+	To   *string `json:"to,omitempty"`
+}
+
+func diffSide(cfg DecryptConfig) string {
+	version := cfg.Version
+	if version == "" {
+		version = client.CurrentVersion
+	}
+	return fmt.Sprintf("%s/%s@%s", cfg.Environment, cfg.Name, version)
+}
+
+// Diff decrypts both sides locally and compares them by key, the From side
+// is fetched by sm and the To side by target, so they can be in different
+// environments.
+func (sm SecretManager) Diff(target SecretManager, cfg DiffConfig) (ConfigDiff, error) {
+	before, err := sm.loadVariables(cfg.From)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("%s: %w", diffSide(cfg.From), err)
+	}
+	after, err := target.loadVariables(cfg.To)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("%s: %w", diffSide(cfg.To), err)
+	}
+	return newConfigDiff(diffSide(cfg.From), diffSide(cfg.To), before, after, cfg.ShowValues), nil
+}
+
+func newConfigDiff(from, to string, before, after []dolores.Variable, showValues bool) ConfigDiff {
+	diff := dolores.DiffVariables(before, after)
+	old, cur := variableValues(before), variableValues(after)
+	change := func(key string, from, to bool) KeyChange {
+		kc := KeyChange{Key: key}
+		if !showValues {
+			return kc
+		}
+		if from {
+			v := old[key]
+			kc.From = &v
+		}
+		if to {
+			v := cur[key]
+			kc.To = &v
+		}
+		return kc
+	}
+	result := ConfigDiff{From: from, To: to, Added: []KeyChange{}, Removed: []KeyChange{}, Changed: []KeyChange{}}
+	for _, k := range diff.Added {
+		result.Added = append(result.Added, change(k, false, true))
+	}
+	for _, k := range diff.Removed {
+		result.Removed = append(result.Removed, change(k, true, false))
+	}
+	for _, k := range diff.Changed {
+		result.Changed = append(result.Changed, change(k, true, true))
+	}
+	return result
+}
+
+// loadVariables decrypts the config, a raw config is compared as a whole.
+func (sm SecretManager) loadVariables(cfg DecryptConfig) ([]dolores.Variable, error) {
+	ef, err := sm.Load(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return variablesOf(ef), nil
+}
+
+func variablesOf(ef *dolores.EnvFile) []dolores.Variable {
+	if ef.Format == dolores.FormatRaw {
+		return []dolores.Variable{{Key: []byte(rawFileKey), Value: ef.Bytes()}}
+	}
+	return ef.Variables
+}
+
+func variableValues(vars []dolores.Variable) map[string]string {
+	res := make(map[string]string, len(vars))
+	for _, v := range vars {
+		res[string(v.Key)] = string(v.Value)
+	}
+	return res
+}
+
+// WriteDiff prints the diff as JSON or one key per line, prefixed with +,
+// - or ~ for added, removed and changed keys.
+func (sm SecretManager) WriteDiff(cfg DiffConfig, diff ConfigDiff) error {
+	out := cfg.output()
+	if cfg.JSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", diff.From, diff.To)
+	if diff.Empty() {
+		fmt.Fprintln(out, "no differences")
+		return nil
+	}
+	for _, kc := range diff.Added {
+		fmt.Fprintf(out, "+ %s=%s\n", displayKey(kc.Key), displayValue(kc.To))
+	}
+	for _, kc := range diff.Removed {
+		fmt.Fprintf(out, "- %s=%s\n", displayKey(kc.Key), displayValue(kc.From))
+	}
+	for _, kc := range diff.Changed {
+		fmt.Fprintf(out, "~ %s: %s -> %s\n", displayKey(kc.Key), displayValue(kc.From), displayValue(kc.To))
+	}
+	return nil
+}
+
+func displayKey(key string) string {
+	if key == rawFileKey {
+		return "(whole file)"
+	}
+	return key
+}
+
+func displayValue(value *string) string {
+	if value == nil {
+		return maskedValue
+	}
+	return fmt.Sprintf("%q", *value)
+}
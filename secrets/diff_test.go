@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/scalescape/dolores"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	diffBefore = []dolores.Variable{
+		{Key: []byte("API_URL"), Value: []byte("https://staging")},
+		{Key: []byte("TOKEN"), Value: []byte("old")},
+	}
+	diffAfter = []dolores.Variable{
+		{Key: []byte("TOKEN"), Value: []byte("new")},
+		{Key: []byte("LOG_LEVEL"), Value: []byte("warn")},
+	}
+)
+
+func writeDiff(t *testing.T, cfg DiffConfig, diff ConfigDiff) string {
+	t.Helper()
+	out := new(bytes.Buffer)
+	cfg.Out = out
+	require.NoError(t, SecretManager{}.WriteDiff(cfg, diff))
+	return out.String()
+}
+
+func TestShouldMaskValuesInDiff(t *testing.T) {
+	diff := newConfigDiff("staging/api@current", "production/api@current", diffBefore, diffAfter, false)
+
+	assert.Equal(t, []KeyChange{{Key: "LOG_LEVEL"}}, diff.Added)
+	assert.Equal(t, []KeyChange{{Key: "API_URL"}}, diff.Removed)
+	assert.Equal(t, []KeyChange{{Key: "TOKEN"}}, diff.Changed)
+	assert.Equal(t, `--- staging/api@current
++++ production/api@current
++ LOG_LEVEL=********
+- API_URL=********
+~ TOKEN: ******** -> ********
+`, writeDiff(t, DiffConfig{}, diff))
+}
+
+func TestShouldShowValuesInDiff(t *testing.T) {
+	diff := newConfigDiff("staging/api@current", "production/api@current", diffBefore, diffAfter, true)
+
+	assert.Equal(t, `--- staging/api@current
++++ production/api@current
++ LOG_LEVEL="warn"
+- API_URL="https://staging"
+~ TOKEN: "old" -> "new"
+`, writeDiff(t, DiffConfig{ShowValues: true}, diff))
+}
+
+func TestShouldWriteDiffAsJSON(t *testing.T) {
+	masked := newConfigDiff("staging/api@current", "staging/api@v1", diffBefore, diffAfter, false)
+	shown := newConfigDiff("staging/api@current", "staging/api@v1", diffBefore, diffAfter, true)
+	empty := newConfigDiff("staging/api@current", "staging/api@v1", diffBefore, diffBefore, true)
+
+	assert.JSONEq(t, `{
+		"from": "staging/api@current", "to": "staging/api@v1",
+		"added": [{"key": "LOG_LEVEL"}], "removed": [{"key": "API_URL"}], "changed": [{"key": "TOKEN"}]
+	}`, writeDiff(t, DiffConfig{JSON: true}, masked))
+	assert.JSONEq(t, `{
+		"from": "staging/api@current", "to": "staging/api@v1",
+		"added": [{"key": "LOG_LEVEL", "to": "warn"}],
+		"removed": [{"key": "API_URL", "from": "https://staging"}],
+		"changed": [{"key": "TOKEN", "from": "old", "to": "new"}]
+	}`, writeDiff(t, DiffConfig{JSON: true}, shown))
+	assert.JSONEq(t, `{"from": "staging/api@current", "to": "staging/api@v1", "added": [], "removed": [], "changed": []}`,
+		writeDiff(t, DiffConfig{JSON: true}, empty))
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/scalescape/dolores"
-	"github.com/scalescape/dolores/client"
 	"github.com/scalescape/dolores/lib"
 )
 
@@ -16,29 +15,26 @@ type EditConfig struct {
 
 // revive:disable function-length
 func (sm SecretManager) Edit(cfg EditConfig) error {
-	req := client.FetchSecretRequest{Name: cfg.Name, Environment: cfg.Environment}
-	data, err := sm.client.FetchSecrets(req)
+	ef, err := sm.Load(cfg.DecryptConfig)
 	if err != nil {
 		return err
 	}
-	dc := &dolores.DecryptConfig{KeyFile: cfg.KeyFile, Key: cfg.Key}
-	dec, err := dolores.NewDecryptor(dc)
-	if err != nil {
-		return err
+	if ef.Format == dolores.FormatRaw {
+		return fmt.Errorf("%s: %w, decrypt it with --raw and encrypt it again", cfg.Name, dolores.ErrRawConfig)
 	}
-	result, err := dec.Decrypt(data)
-	if err != nil {
-		return err
+	fname := cfg.Name
+	if ef.Format != dolores.FormatDotenv {
+		fname += ef.Format.Ext()
 	}
-	f, err := lib.CreateTempFile(cfg.Name)
+	f, err := lib.CreateTempFile(fname)
 	if err != nil {
 		return fmt.Errorf("error creating file: %w", err)
 	}
 	defer f.Close()
-	if _, err := f.Write(result); err != nil {
+	if _, err := f.Write(ef.Bytes()); err != nil {
 		return err
 	}
-	if err := sm.editFile(f.Name(), cfg); err != nil {
+	if err := sm.editFile(f.Name(), ef.Format, cfg); err != nil {
 		return err
 	}
 	if err := os.Remove(f.Name()); err != nil {
@@ -47,7 +43,7 @@ func (sm SecretManager) Edit(cfg EditConfig) error {
 	return nil
 }
 
-func (sm SecretManager) editFile(fname string, cfg EditConfig) error {
+func (sm SecretManager) editFile(fname string, format dolores.Format, cfg EditConfig) error {
 	before, err := lib.Hash(fname)
 	if err != nil {
 		return err
@@ -62,7 +58,7 @@ func (sm SecretManager) editFile(fname string, cfg EditConfig) error {
 	}
 	if !bytes.Equal(before, after) {
 		sm.log.Debug().Msgf("Updating changes to remote")
-		ereq := EncryptConfig{Environment: cfg.Environment, FileName: fname, Name: cfg.Name}
+		ereq := EncryptConfig{Environment: cfg.Environment, FileName: fname, Name: cfg.Name, Format: format}
 		if err := sm.Encrypt(ereq); err != nil {
 			return fmt.Errorf("error uploading changes to remote: %w", err)
 		}
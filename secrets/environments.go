@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+)
+
+type EnvironmentConfig struct {
+	Name string
+	// From is the environment the new one is set up like.
+	From string
+}
+
+// ListEnvironments prints the environments registered remotely and the
+// ones configured on disk.
+func (sm SecretManager) ListEnvironments(out io.Writer) error {
+	remote, err := sm.client.ListEnvironments()
+	if err != nil {
+		return fmt.Errorf("failed to list environments: %w", err)
+	}
+	local := make(map[string]bool)
+	if d, err := config.LoadFromDisk(); err == nil {
+		for _, name := range d.Names() {
+			local[name] = true
+		}
+	}
+	lineFormat := "%-30s %s\n"
+	fmt.Fprintf(out, lineFormat, "Name", "Status")
+	for _, name := range remote {
+		status := "not configured, run `dolores --env " + name + " init`"
+		if local[name] {
+			status = "configured"
+		}
+		delete(local, name)
+		fmt.Fprintf(out, lineFormat, name, status)
+	}
+	others := make([]string, 0, len(local))
+	for name := range local {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		fmt.Fprintf(out, lineFormat, name, "configured, stored elsewhere")
+	}
+	return nil
+}
+
+// CreateEnvironment registers the environment, already configured on disk,
+// and publishes the keys of the user in it.
+func (sm SecretManager) CreateEnvironment(cfg EnvironmentConfig) error {
+	req := client.EnvironmentRequest{Environment: cfg.Name, From: cfg.From}
+	if err := sm.client.CreateEnvironment(req); err != nil {
+		return fmt.Errorf("failed to create environment: %w", err)
+	}
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	ec := d.Environments[cfg.Name]
+	pub, err := dolores.ReadPublicKey(ec.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error reading public key: %s %w", ec.KeyFile, err)
+	}
+	if err := sm.publishKey(cfg.Name, ec.UserID, pub); err != nil {
+		return err
+	}
+	sm.log.Info().Msgf("created environment %s", cfg.Name)
+	return nil
+}
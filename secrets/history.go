@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+	"github.com/scalescape/dolores/lib"
+)
+
+type HistoryConfig struct {
+	Environment string
+	Name        string
+	Out         io.Writer
+}
+
+func (c HistoryConfig) output() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+func (c HistoryConfig) Valid() error {
+	if err := config.ValidEnvironment(c.Environment); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
+	}
+	if c.Name == "" {
+		return ErrInvalidConfigName
+	}
+	return nil
+}
+
+// History lists the versions of the config, newest first, with the author
+// recorded in the envelope of each.
+func (sm SecretManager) History(cfg HistoryConfig) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	req := client.FetchSecretRequest{Environment: cfg.Environment, Name: cfg.Name}
+	versions, err := sm.client.ListSecretVersions(req)
+	if err != nil {
+		return fmt.Errorf("failed to list versions of %s: %w", cfg.Name, err)
+	}
+	lineFormat := "%-34s %-20s %s\n"
+	out := cfg.output()
+	fmt.Fprintf(out, lineFormat, "VERSION", "UPDATED", "AUTHOR")
+	for _, v := range versions {
+		req.Version = v.Version
+		name := v.Version
+		if v.Current && name != client.CurrentVersion {
+			name += " (current)"
+		}
+		fmt.Fprintf(out, lineFormat, name, v.UpdatedAt.UTC().Format(time.DateTime), sm.versionAuthor(req))
+	}
+	return nil
+}
+
+func (sm SecretManager) versionAuthor(req client.FetchSecretRequest) string {
+	env, _, src, err := sm.fetchRequestEnvelope(req)
+	if err != nil {
+		return err.Error()
+	}
+	defer src.Close()
+	if env == nil {
+		return "unknown, no envelope"
+	}
+	return env.Author
+}
+
+type RollbackConfig struct {
+	DecryptConfig
+	Version string
+}
+
+func (c RollbackConfig) Valid() error {
+	if err := c.DecryptConfig.Valid(); err != nil {
+		return err
+	}
+	if c.Version == "" || c.Version == client.CurrentVersion {
+		return client.ErrInvalidVersion
+	}
+	return nil
+}
+
+// Rollback uploads a previous version of the config as it was, after it's
+// decrypted in full so only a verified version replaces the current one.
+func (sm SecretManager) Rollback(cfg RollbackConfig) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	req := client.FetchSecretRequest{Environment: cfg.Environment, Name: cfg.Name, Version: cfg.Version}
+	data, err := sm.client.FetchSecrets(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch version %s of %s: %w", cfg.Version, cfg.Name, err)
+	}
+	env, payload, err := dolores.ReadEnvelope(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Passphrase == nil {
+		cfg.Passphrase = dolores.RememberPassphrase(lib.ReadPassphrase)
+	}
+	if _, err := sm.decryptAll(cfg.DecryptConfig, env, payload); err != nil {
+		return fmt.Errorf("failed to verify version %s: %w", cfg.Version, err)
+	}
+	if err := sm.client.UploadSecretStream(client.SecretStream{Environment: cfg.Environment, Name: cfg.Name, Data: bytes.NewReader(data)}); err != nil {
+		return fmt.Errorf("error uploading: %w", err)
+	}
+	sm.log.Info().Msgf("rolled back %s to version %s", cfg.Name, cfg.Version)
+	if change := sm.recipientChange(cfg.Environment, env); !change.upToDate() {
+		sm.log.Warn().Msgf("version %s isn't encrypted for the current keys (%s), run dolores rekey --name %s", cfg.Version, change, cfg.Name)
+	}
+	return nil
+}
+
+func (sm SecretManager) recipientChange(environment string, env *dolores.Envelope) recipientChange {
+	recps, err := sm.recipients(environment)
+	if err != nil {
+		sm.log.Warn().Msgf("failed to compare recipients: %v", err)
+		return recipientChange{}
+	}
+	current := make(map[string]bool, len(recps))
+	for _, r := range recps {
+		current[dolores.Fingerprint(r)] = true
+	}
+	return compareRecipients(env, current)
+}
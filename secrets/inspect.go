@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+)
+
+type InspectConfig struct {
+	Environment string
+	Name        string
+	Out         io.Writer
+}
+
+func (c InspectConfig) output() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+// Inspect prints the envelope of the remote config, it doesn't need a key.
+func (sm SecretManager) Inspect(cfg InspectConfig) error {
+	if cfg.Name == "" {
+		return ErrInvalidConfigName
+	}
+	src, err := sm.client.FetchSecretStream(client.FetchSecretRequest{Environment: cfg.Environment, Name: cfg.Name})
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	env, payload, err := dolores.ReadEnvelope(src)
+	if err != nil {
+		return err
+	}
+	out := cfg.output()
+	lineFormat := "%-14s %s\n"
+	fmt.Fprintf(out, lineFormat, "name:", cfg.Name)
+	if env == nil {
+		fmt.Fprintf(out, lineFormat, "envelope:", "none, uploaded by an older version")
+		return nil
+	}
+	status := "verified"
+	if err := env.VerifyContent(payload); err != nil {
+		status = err.Error()
+	}
+	fmt.Fprintf(out, lineFormat, "version:", fmt.Sprint(env.Version))
+	fmt.Fprintf(out, lineFormat, "author:", env.Author)
+	fmt.Fprintf(out, lineFormat, "created_at:", env.CreatedAt.Format(time.DateTime))
+	fmt.Fprintf(out, lineFormat, "tool:", env.Tool)
+	fmt.Fprintf(out, lineFormat, "recipients:", strings.Join(env.Recipients, "\n"+strings.Repeat(" ", 15)))
+	fmt.Fprintf(out, lineFormat, "content_hash:", fmt.Sprintf("%s (%s)", env.ContentHash, status))
+	fmt.Fprintf(out, lineFormat, "signer:", sm.signerStatus(cfg.Environment, env))
+	if len(env.NeedsRotation) > 0 {
+		fmt.Fprintf(out, lineFormat, "rotate:", strings.Join(env.NeedsRotation, ", "))
+	}
+	return nil
+}
+
+func (sm SecretManager) signerStatus(environment string, env *dolores.Envelope) string {
+	if env.Signature == "" {
+		return "unsigned"
+	}
+	signers, err := sm.signers(environment)
+	if err != nil {
+		return fmt.Sprintf("%s (%v)", env.Signer, err)
+	}
+	signer, err := env.VerifySignature(signers)
+	if err != nil {
+		return fmt.Sprintf("%s (%v)", env.Signer, err)
+	}
+	return fmt.Sprintf("%s (verified %s)", env.Signer, signer.ID)
+}
@@ -0,0 +1,290 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+	"github.com/scalescape/dolores/lib"
+)
+
+var ErrKeyringRequired = errors.New("no keyring yet, create it with `dolores keys approve --user <your id>`")
+
+// publicKeyEnv overrides the recipients with a single key, bypassing the
+// keyring and the published keys.
+const publicKeyEnv = "DOLORES_PUBLIC_KEY"
+
+// keyring fetches the team keyring and checks it against the pinned copy.
+func (sm SecretManager) keyring(env string) (*dolores.Keyring, error) {
+	data, err := sm.client.GetKeyring(env)
+	if err != nil {
+		return nil, err
+	}
+	k, err := dolores.ParseKeyring(data)
+	if err != nil {
+		return nil, err
+	}
+	first, err := dolores.TrustKeyring(config.KeyringPin(env), k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trust keyring: %w", err)
+	}
+	if first {
+		sm.log.Warn().Msgf("trusting keyring updated by %s on first use", k.UpdatedBy)
+	}
+	return k, nil
+}
+
+// recipients returns the keys in the verified keyring, keys published
+// without an approval are skipped. Server managed keys are used as is.
+func (sm SecretManager) recipients(env string) ([]string, error) {
+	if key := os.Getenv(publicKeyEnv); key != "" {
+		sm.log.Warn().Msgf("encrypting only to %s, the keyring of %s is ignored", publicKeyEnv, env)
+		return []string{key}, nil
+	}
+	resp, err := sm.client.GetOrgPublicKeys(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keys: %w", err)
+	}
+	published := resp.RecipientList()
+	k, err := sm.keyring(env)
+	if errors.Is(err, client.ErrMethodUndefined) {
+		return published, nil
+	} else if errors.Is(err, client.ErrKeyringNotFound) {
+		return nil, ErrKeyringRequired
+	} else if err != nil {
+		return nil, err
+	}
+	if pending := k.Unapproved(published); len(pending) > 0 {
+		sm.log.Warn().Msgf("skipping %d keys not in the keyring, approve them with `dolores keys approve`", len(pending))
+	}
+	return k.Recipients(), nil
+}
+
+// signers returns the signing keys of the verified keyring.
+func (sm SecretManager) signers(env string) ([]dolores.Signer, error) {
+	k, err := sm.keyring(env)
+	if errors.Is(err, client.ErrMethodUndefined) {
+		signers, err := sm.client.GetSigners(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signing keys: %w", err)
+		}
+		return toSigners(signers), nil
+	} else if errors.Is(err, client.ErrKeyringNotFound) {
+		return nil, ErrKeyringRequired
+	} else if err != nil {
+		return nil, err
+	}
+	return k.Signers(), nil
+}
+
+type ApproveConfig struct {
+	Environment string
+	UserID      string
+}
+
+// Approve adds the keys the user published to the keyring, signed by the
+// current user. The keyring is created when it doesn't exist yet.
+func (sm SecretManager) Approve(cfg ApproveConfig) error {
+	err := sm.updateKeyring(cfg.Environment, func(k *dolores.Keyring, me string) error {
+		return sm.addKeys(k, cfg.Environment, cfg.UserID, cfg.UserID, me)
+	})
+	if err != nil {
+		return err
+	}
+	sm.log.Info().Msgf("approved keys of %s", cfg.UserID)
+	return nil
+}
+
+// updateKeyring applies the change to the verified keyring and uploads it
+// signed by the current user.
+func (sm SecretManager) updateKeyring(env string, change func(k *dolores.Keyring, me string) error) error {
+	k, err := sm.keyring(env)
+	notFound := errors.Is(err, client.ErrKeyringNotFound)
+	if err != nil && !notFound {
+		return err
+	}
+	me, key, err := loadSigningKey(env)
+	if err != nil {
+		return err
+	}
+	if notFound {
+		k = new(dolores.Keyring)
+		if err := sm.addKeys(k, env, me, me, me); err != nil {
+			return err
+		}
+	}
+	if err := change(k, me); err != nil {
+		return err
+	}
+	if err := k.Sign(me, key); err != nil {
+		return err
+	}
+	data, err := k.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := sm.client.UploadKeyring(env, data); err != nil {
+		return fmt.Errorf("failed to upload keyring: %w", err)
+	}
+	_, err = dolores.TrustKeyring(config.KeyringPin(env), k)
+	return err
+}
+
+func (sm SecretManager) addKeys(k *dolores.Keyring, env, userID, addedBy, approver string) error {
+	keys, err := sm.client.GetUserKeys(env, userID)
+	if err != nil {
+		return err
+	}
+	k.Add(dolores.KeyringEntry{
+		UserID:     userID,
+		PublicKey:  keys.PublicKey,
+		SigningKey: keys.SigningKey,
+		AddedBy:    addedBy,
+		AddedAt:    time.Now().UTC().Truncate(time.Second),
+		ApprovedBy: approver,
+	})
+	return nil
+}
+
+type KeysConfig struct {
+	Environment string
+	UserID      string
+	PublicKey   string
+	Out         io.Writer
+}
+
+func (c KeysConfig) output() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+// ListKeys prints the published keys and whether they're in the keyring.
+func (sm SecretManager) ListKeys(cfg KeysConfig) error {
+	keys, err := sm.client.ListKeys(cfg.Environment)
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+	k, err := sm.keyring(cfg.Environment)
+	if err != nil && !errors.Is(err, client.ErrMethodUndefined) && !errors.Is(err, client.ErrKeyringNotFound) {
+		return err
+	}
+	lineFormat := "%-20s %-52s %-20s %s\n"
+	fmt.Fprintf(cfg.output(), lineFormat, "User", "Fingerprint", "Added At (UTC)", "Status")
+	for _, uk := range keys {
+		fmt.Fprintf(cfg.output(), lineFormat, uk.UserID, dolores.Fingerprint(uk.PublicKey),
+			uk.AddedAt.UTC().Format(time.DateTime), keyStatus(k, uk))
+	}
+	return nil
+}
+
+func keyStatus(k *dolores.Keyring, uk client.UserKeys) string {
+	if k == nil {
+		return "active"
+	}
+	e, ok := k.Entry(uk.UserID)
+	if !ok || dolores.Fingerprint(e.PublicKey) != dolores.Fingerprint(uk.PublicKey) {
+		return "pending approval"
+	}
+	return "approved by " + e.ApprovedBy
+}
+
+// AddKey publishes the key of a user who can't run init and approves it.
+func (sm SecretManager) AddKey(cfg KeysConfig) error {
+	if cfg.UserID == "" {
+		return ErrInvalidUser
+	}
+	if _, err := dolores.ParseRecipient(cfg.PublicKey); err != nil {
+		return fmt.Errorf("invalid public key for %s: %w", cfg.UserID, err)
+	}
+	uk := client.UserKeys{UserID: cfg.UserID, PublicKey: strings.TrimSpace(cfg.PublicKey)}
+	if err := sm.client.AddKey(cfg.Environment, uk); err != nil {
+		return fmt.Errorf("failed to add key: %w", err)
+	}
+	err := sm.updateKeyring(cfg.Environment, func(k *dolores.Keyring, me string) error {
+		return sm.addKeys(k, cfg.Environment, cfg.UserID, me, me)
+	})
+	if err != nil && !errors.Is(err, client.ErrMethodUndefined) {
+		return err
+	}
+	sm.log.Info().Msgf("added key of %s", cfg.UserID)
+	return nil
+}
+
+// RemoveKey drops the user from the keyring and deletes their keys, configs
+// they could read stay readable to them until they're rotated.
+func (sm SecretManager) RemoveKey(cfg KeysConfig) error {
+	if cfg.UserID == "" {
+		return ErrInvalidUser
+	}
+	err := sm.updateKeyring(cfg.Environment, func(k *dolores.Keyring, _ string) error {
+		if !k.Remove(cfg.UserID) {
+			sm.log.Warn().Msgf("%s isn't in the keyring", cfg.UserID)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, client.ErrMethodUndefined) {
+		return err
+	}
+	if err := sm.client.RemoveKey(cfg.Environment, cfg.UserID); err != nil {
+		return fmt.Errorf("failed to remove key: %w", err)
+	}
+	sm.log.Info().Msgf("removed key of %s, configs uploaded before can still be read with it until re-encrypted", cfg.UserID)
+	return nil
+}
+
+// ShowMine prints the keys of the current user.
+func (sm SecretManager) ShowMine(cfg KeysConfig) error {
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	ec, ok := d.Environments[cfg.Environment]
+	if !ok {
+		return fmt.Errorf("%s: %w", cfg.Environment, ErrInvalidEnvironment)
+	}
+	pub, err := dolores.ReadPublicKey(ec.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	out, lineFormat := cfg.output(), "%-13s %s\n"
+	fmt.Fprintf(out, lineFormat, "user:", ec.UserID)
+	fmt.Fprintf(out, lineFormat, "key file:", ec.KeyFile)
+	fmt.Fprintf(out, lineFormat, "public key:", pub)
+	fmt.Fprintf(out, lineFormat, "fingerprint:", dolores.Fingerprint(pub))
+	if ec.SigningKeyFile != "" {
+		if key, err := dolores.LoadSigningKey(ec.SigningKeyFile, lib.ReadPassphrase); err == nil {
+			fmt.Fprintf(out, lineFormat, "signing key:", key.Public())
+		}
+	}
+	k, err := sm.keyring(cfg.Environment)
+	if err != nil && !errors.Is(err, client.ErrMethodUndefined) && !errors.Is(err, client.ErrKeyringNotFound) {
+		return err
+	}
+	fmt.Fprintf(out, lineFormat, "status:", keyStatus(k, client.UserKeys{UserID: ec.UserID, PublicKey: pub}))
+	return nil
+}
+
+// loadSigningKey returns the id and signing key of the current user.
+func loadSigningKey(env string) (string, *dolores.SigningKey, error) {
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return "", nil, fmt.Errorf("dolores not initialized yet: %w: %w", ErrInvalidKeyFile, err)
+	}
+	ec := d.Environments[env]
+	if ec.SigningKeyFile == "" {
+		return "", nil, fmt.Errorf("no signing key for %s, run init: %w", env, ErrInvalidKeyFile)
+	}
+	key, err := dolores.LoadSigningKey(ec.SigningKeyFile, lib.ReadPassphrase)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+	return ec.UserID, key, nil
+}
@@ -1,30 +1,48 @@
 package secrets
 
 import (
-	"encoding/base64"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/scalescape/dolores"
 	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
 )
 
 type secClient interface {
 	FetchSecrets(req client.FetchSecretRequest) ([]byte, error)
 	UploadSecrets(req client.EncryptedConfig) error
+	UploadSecretStream(req client.SecretStream) error
+	FetchSecretStream(req client.FetchSecretRequest) (io.ReadCloser, error)
 	GetOrgPublicKeys(env string) (client.OrgPublicKeys, error)
 	GetSecretList(cfg client.SecretListConfig) ([]client.SecretObject, error)
+	GetSigners(env string) ([]client.Signer, error)
+	GetKeyring(env string) ([]byte, error)
+	UploadKeyring(env string, data []byte) error
+	GetUserKeys(env, userID string) (client.UserKeys, error)
+	ListKeys(env string) ([]client.UserKeys, error)
+	AddKey(env string, keys client.UserKeys) error
+	RemoveKey(env, userID string) error
+	ListEnvironments() ([]string, error)
+	CreateEnvironment(req client.EnvironmentRequest) error
+	DeleteSecret(req client.DeleteSecretRequest) error
+	RenameSecret(req client.RenameSecretRequest) error
+	ListSecretVersions(req client.FetchSecretRequest) ([]client.SecretVersion, error)
 }
 
 type EncryptConfig struct {
 	Environment string
 	FileName    string
 	Name        string
+	Format      dolores.Format
+	// Raw stores the file as is, with ContentType detected when empty.
+	Raw         bool
+	ContentType string
 }
 
 var (
@@ -33,6 +51,8 @@ var (
 	ErrInvalidConfigName    = errors.New("invalid config name")
 	ErrInvalidEnvironment   = errors.New("invalid environment")
 	ErrInvalidOutput        = errors.New("invalid output writer")
+	ErrInvalidUser          = errors.New("invalid user id")
+	ErrSignatureRequired    = errors.New("refusing unverified config, pass --allow-unsigned to read it anyway")
 )
 
 type SecretManager struct {
@@ -40,46 +60,94 @@ type SecretManager struct {
 	log    zerolog.Logger
 }
 
-// revive:disable function-length
 func (sm SecretManager) Encrypt(req EncryptConfig) error {
 	env, file, name := req.Environment, req.FileName, req.Name
 	log := sm.log.With().Str("cmd", "config.encrypt").Str("environment",
 		env).Logger()
-	envFile, err := dolores.LoadEnvFile(file)
+	if req.Raw {
+		return sm.encryptRaw(req)
+	}
+	envFile, err := dolores.LoadFile(file, req.Format)
 	if err != nil {
 		return fmt.Errorf("failed to load file: %w", err)
 	}
-	resp, err := sm.client.GetOrgPublicKeys(env)
+	enc, err := sm.encryptor(env)
 	if err != nil {
-		return fmt.Errorf("failed to get keys: %w", err)
+		return err
 	}
-	recps := make([]string, len(resp.Recipients))
-	for i, r := range resp.Recipients {
-		recps[i] = r.PublicKey
+	log.Debug().Msgf("uploading encrypted file to server: %s", name)
+	return sm.upload(enc, env, name, bytes.NewReader(envFile.Payload()))
+}
+
+// encryptRaw streams the file from disk, so it's never fully loaded.
+func (sm SecretManager) encryptRaw(req EncryptConfig) error {
+	f, err := os.Open(req.FileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
 	}
-	enc, err := dolores.NewEncryptor(recps...)
+	defer f.Close()
+	enc, err := sm.encryptor(req.Environment)
 	if err != nil {
-		return fmt.Errorf("error creating encryptor: %w", err)
+		return err
 	}
-	data, err := enc.Encrypt(envFile.Variables)
+	sm.log.Debug().Msgf("uploading raw file to server: %s", req.Name)
+	return sm.upload(enc, req.Environment, req.Name, dolores.RawPayload(req.FileName, req.ContentType, f))
+}
+
+func (sm SecretManager) encryptor(env string) (*dolores.Encryptor, error) {
+	recps, err := sm.recipients(env)
 	if err != nil {
-		return fmt.Errorf("error encrypting: %w", err)
+		return nil, err
 	}
-	log.Debug().Msgf("uploading encrypted file to server: %s", name)
-	ureq := client.EncryptedConfig{
-		Environment: env,
-		Name:        name,
-		Data:        base64.StdEncoding.EncodeToString(data),
+	return sm.newEncryptor(env, recps)
+}
+
+func (sm SecretManager) newEncryptor(env string, recps []string) (*dolores.Encryptor, error) {
+	enc, err := dolores.NewEncryptor(recps...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating encryptor: %w", err)
+	}
+	userID, key, err := loadSigningKey(env)
+	if errors.Is(err, ErrInvalidKeyFile) {
+		sm.log.Warn().Msgf("uploading unsigned config, run init to create a signing key")
+		return enc, nil
+	} else if err != nil {
+		return nil, err
 	}
-	return sm.client.UploadSecrets(ureq)
+	enc.Author, enc.SigningKey = userID, key
+	return enc, nil
+}
+
+// upload encrypts the content while it's being uploaded.
+func (sm SecretManager) upload(enc *dolores.Encryptor, env, name string, r io.Reader) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(enc.EncryptTo(pw, r))
+	}()
+	defer pr.Close()
+	if err := sm.client.UploadSecretStream(client.SecretStream{Environment: env, Name: name, Data: pr}); err != nil {
+		return fmt.Errorf("error uploading: %w", err)
+	}
+	return nil
 }
 
 type DecryptConfig struct {
 	Name        string
 	Environment string
-	KeyFile     string
-	Key         string
-	Out         io.Writer
+	// Version is a previous version of the config, as listed by its
+	// history, the current one is used when it's empty.
+	Version string
+	KeyFile string
+	Key     string
+	Out     io.Writer
+	Render  dolores.RenderConfig
+	// Raw writes the decrypted content as is, without parsing it.
+	Raw bool
+	// AllowUnsigned reads configs that aren't signed by a known team
+	// member, with a warning.
+	AllowUnsigned bool
+	// Passphrase unlocks a protected key file, it prompts when unset.
+	Passphrase dolores.PassphraseFunc
 }
 
 func (c DecryptConfig) Output() io.Writer {
@@ -96,8 +164,8 @@ func (c DecryptConfig) Valid() error {
 	if c.Name == "" {
 		return ErrInvalidConfigName
 	}
-	if strings.ToLower(c.Environment) != "production" && strings.ToLower(c.Environment) != "staging" {
-		return ErrInvalidEnvironment
+	if err := config.ValidEnvironment(c.Environment); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
 	}
 	if c.Out == nil {
 		return ErrInvalidOutput
@@ -105,31 +173,133 @@ func (c DecryptConfig) Valid() error {
 	return nil
 }
 
-// revive:disable function-length
 func (sm SecretManager) Decrypt(cfg DecryptConfig) error {
-	if err := cfg.Valid(); err != nil {
-		return fmt.Errorf("invalid config: %w: %w", ErrInvalidDecryptConfig, err)
+	if cfg.Raw {
+		return sm.decryptRaw(cfg)
 	}
-	req := client.FetchSecretRequest{Name: cfg.Name, Environment: cfg.Environment}
-	data, err := sm.client.FetchSecrets(req)
+	ef, err := sm.Load(cfg)
 	if err != nil {
 		return err
 	}
-	dc := &dolores.DecryptConfig{KeyFile: cfg.KeyFile, Key: cfg.Key}
-	dec, err := dolores.NewDecryptor(dc)
-	if err != nil {
+	if cfg.Render.Format != "" {
+		if err := ef.Render(cfg.Output(), cfg.Render); errors.Is(err, dolores.ErrRawConfig) {
+			return fmt.Errorf("%s: %w, read it without --output", cfg.Name, err)
+		} else if err != nil {
+			return err
+		}
+		return nil
+	}
+	if _, err := cfg.Output().Write(ef.Bytes()); err != nil {
 		return err
 	}
-	result, err := dec.Decrypt(data)
+	return nil
+}
+
+// Load fetches, decrypts and parses the remote config.
+func (sm SecretManager) Load(cfg DecryptConfig) (*dolores.EnvFile, error) {
+	rdr, err := sm.open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	result, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", cfg.Name, err)
+	}
+	ef, err := dolores.ParsePayload(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", cfg.Name, err)
+	}
+	return ef, nil
+}
+
+func (sm SecretManager) decryptRaw(cfg DecryptConfig) error {
+	rdr, err := sm.open(cfg)
 	if err != nil {
 		return err
 	}
-	if _, err := cfg.Output().Write(result); err != nil {
+	defer rdr.Close()
+	header, content, err := dolores.ReadPayloadHeader(rdr)
+	if err != nil {
 		return err
 	}
+	sm.log.Debug().Msgf("writing %s config %s of type %s", header.Format, cfg.Name, header.ContentType)
+	if _, err := io.Copy(cfg.Output(), content); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", cfg.Name, err)
+	}
 	return nil
 }
 
+// revive:disable function-length
+
+// open returns a reader decrypting the remote config while it's fetched.
+func (sm SecretManager) open(cfg DecryptConfig) (io.ReadCloser, error) {
+	if err := cfg.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w: %w", ErrInvalidDecryptConfig, err)
+	}
+	dec, err := sm.decryptor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	req := client.FetchSecretRequest{Name: cfg.Name, Environment: cfg.Environment, Version: cfg.Version}
+	src, err := sm.client.FetchSecretStream(req)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := dec.DecryptFrom(src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{rdr, src}, nil
+}
+
+// decryptor verifies the signature of the config before decrypting it.
+func (sm SecretManager) decryptor(cfg DecryptConfig) (dolores.Decryptor, error) {
+	signers, err := sm.signers(cfg.Environment)
+	if err != nil && !cfg.AllowUnsigned {
+		return dolores.Decryptor{}, err
+	} else if err != nil {
+		sm.log.Warn().Msgf("can't verify config %s: %v", cfg.Name, err)
+	}
+	dc := &dolores.DecryptConfig{
+		KeyFile: cfg.KeyFile, Key: cfg.Key, Passphrase: cfg.Passphrase,
+		VerifyEnvelope: sm.verifier(cfg, signers),
+	}
+	return dolores.NewDecryptor(dc)
+}
+
+// verifier refuses configs that aren't signed by a known team member,
+// unless they're explicitly allowed.
+func (sm SecretManager) verifier(cfg DecryptConfig, known []dolores.Signer) func(*dolores.Envelope) error {
+	return func(env *dolores.Envelope) error {
+		err := dolores.ErrUnsigned
+		if env != nil {
+			var signer dolores.Signer
+			if signer, err = env.VerifySignature(known); err == nil {
+				sm.log.Debug().Msgf("config %s signed by %s", cfg.Name, signer.ID)
+				return nil
+			}
+		}
+		if cfg.AllowUnsigned {
+			sm.log.Warn().Msgf("config %s isn't verified: %v", cfg.Name, err)
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrSignatureRequired, err)
+	}
+}
+
+func toSigners(signers []client.Signer) []dolores.Signer {
+	known := make([]dolores.Signer, len(signers))
+	for i, s := range signers {
+		known[i] = dolores.Signer{ID: s.UserID, PublicKey: s.PublicKey}
+	}
+	return known
+}
+
 type ListSecretConfig struct {
 	Environment string
 	Out         io.Writer
@@ -143,9 +313,8 @@ func (c ListSecretConfig) output() io.Writer {
 }
 
 func (c ListSecretConfig) Valid() error {
-	env := strings.ToLower(c.Environment)
-	if env != "production" && env != "staging" {
-		return ErrInvalidEnvironment
+	if err := config.ValidEnvironment(c.Environment); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEnvironment, err)
 	}
 	return nil
 }
@@ -165,7 +334,7 @@ func (sm SecretManager) ListSecret(cfg ListSecretConfig) error {
 		return err
 	}
 	for _, obj := range resp {
-		if !strings.HasSuffix(obj.Name, ".key") && !obj.IsDir() {
+		if !obj.IsKey() && !obj.IsDir() {
 			createdAt := obj.CreatedAt.Format(time.DateTime)
 			updatedAt := obj.UpdatedAt.Format(time.DateTime)
 			line := []byte(fmt.Sprintf(lineFormat, obj.BaseName(), obj.Location, createdAt, updatedAt))
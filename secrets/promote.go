@@ -0,0 +1,185 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/scalescape/dolores"
+)
+
+var ErrSameEnvironment = errors.New("promote to a different environment")
+
+type PromoteConfig struct {
+	// From is decrypted with the identity of the source environment, To
+	// with the one of the target when the config already exists there.
+	From, To DecryptConfig
+	// Keys limits the promotion to the keys, merged into the target config.
+	Keys []string
+	// Overrides are set after the keys are promoted.
+	Overrides  []dolores.Variable
+	ShowValues bool
+}
+
+func (c PromoteConfig) Valid() error {
+	if err := c.From.Valid(); err != nil {
+		return err
+	}
+	if err := c.To.Valid(); err != nil {
+		return err
+	}
+	if c.From.Environment == c.To.Environment {
+		return ErrSameEnvironment
+	}
+	return nil
+}
+
+// Promotion is the config to upload to the target environment, with the
+// key-level preview of what changes there.
+type Promotion struct {
+	Preview ConfigDiff
+	cfg     PromoteConfig
+	file    *dolores.EnvFile
+	// promoted are the keys given new values in the target.
+	promoted []dolores.Variable
+	// replaced is the envelope of the target config, nil when it's new or
+	// uploaded without one.
+	replaced *dolores.Envelope
+	exists   bool
+}
+
+// PlanPromotion decrypts the source config with sm and the target one with
+// target, and builds the promoted config in memory.
+func (sm SecretManager) PlanPromotion(target SecretManager, cfg PromoteConfig) (Promotion, error) {
+	if err := cfg.Valid(); err != nil {
+		return Promotion{}, fmt.Errorf("invalid config: %w", err)
+	}
+	src, err := sm.Load(cfg.From)
+	if err != nil {
+		return Promotion{}, fmt.Errorf("%s: %w", diffSide(cfg.From), err)
+	}
+	current, replaced, err := target.loadTarget(cfg.To)
+	if err != nil {
+		return Promotion{}, fmt.Errorf("%s: %w", diffSide(cfg.To), err)
+	}
+	before := make([]dolores.Variable, 0)
+	if current != nil {
+		before = variablesOf(current)
+	}
+	keys := promotedKeys(src, cfg)
+	promoted, err := promotedFile(src, current, cfg)
+	if err != nil {
+		return Promotion{}, err
+	}
+	preview := newConfigDiff(diffSide(cfg.To), "promoted from "+diffSide(cfg.From), before, variablesOf(promoted), cfg.ShowValues)
+	return Promotion{
+		Preview: preview, cfg: cfg, file: promoted, promoted: keys,
+		replaced: replaced, exists: current != nil,
+	}, nil
+}
+
+// loadTarget returns the config of the target environment, or nil when
+// it's promoted for the first time.
+func (sm SecretManager) loadTarget(cfg DecryptConfig) (*dolores.EnvFile, *dolores.Envelope, error) {
+	names, err := sm.configNames(cfg.Environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !contains(names, cfg.Name) {
+		return nil, nil, nil
+	}
+	return sm.loadWithEnvelope(cfg)
+}
+
+// promotedFile is the whole source config, or the selected keys merged into
+// the target config, with the overrides set.
+func promotedFile(src, current *dolores.EnvFile, cfg PromoteConfig) (*dolores.EnvFile, error) {
+	promoted := src
+	if len(cfg.Keys) > 0 {
+		var err error
+		if promoted, err = mergeKeys(src, current, cfg.Keys); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range cfg.Overrides {
+		if err := promoted.Set(string(v.Key), v.Value); err != nil {
+			return nil, fmt.Errorf("override %s: %w", v.Key, err)
+		}
+	}
+	return promoted, nil
+}
+
+// promotedKeys are the selected keys, or all the keys of the source config,
+// and the overrides.
+func promotedKeys(src *dolores.EnvFile, cfg PromoteConfig) []dolores.Variable {
+	keys := make([]dolores.Variable, 0, len(cfg.Keys)+len(cfg.Overrides))
+	if len(cfg.Keys) == 0 {
+		for _, v := range variablesOf(src) {
+			keys = append(keys, dolores.Variable{Key: v.Key})
+		}
+	}
+	for _, k := range cfg.Keys {
+		keys = append(keys, dolores.Variable{Key: []byte(k)})
+	}
+	for _, v := range cfg.Overrides {
+		keys = append(keys, dolores.Variable{Key: v.Key})
+	}
+	return keys
+}
+
+func mergeKeys(src, current *dolores.EnvFile, keys []string) (*dolores.EnvFile, error) {
+	merged := current
+	if merged == nil {
+		var err error
+		if merged, err = dolores.ParseEnvFile(nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		value, ok := src.Get(key)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", key, ErrKeyNotFound)
+		}
+		if err := merged.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// Promote encrypts the promoted config to the recipients of the target
+// environment and uploads it, unless the target config changed since the
+// promotion was planned.
+func (sm SecretManager) Promote(p Promotion) error {
+	to := p.cfg.To
+	enc, err := sm.encryptor(to.Environment)
+	if err != nil {
+		return err
+	}
+	if p.replaced != nil {
+		// the promoted keys have new values in the target
+		enc.NeedsRotation = withoutKeys(p.replaced.NeedsRotation, p.promoted)
+	}
+	if err := sm.unchangedTarget(p); err != nil {
+		return err
+	}
+	if err := sm.upload(enc, to.Environment, to.Name, bytes.NewReader(p.file.Payload())); err != nil {
+		return err
+	}
+	sm.log.Info().Msgf("promoted %s from %s to %s", to.Name, p.cfg.From.Environment, to.Environment)
+	return nil
+}
+
+func (sm SecretManager) unchangedTarget(p Promotion) error {
+	if p.exists {
+		return sm.unchanged(p.cfg.To, p.replaced)
+	}
+	names, err := sm.configNames(p.cfg.To.Environment)
+	if err != nil {
+		return err
+	}
+	if contains(names, p.cfg.To.Name) {
+		return fmt.Errorf("%s: %w", p.cfg.To.Name, ErrConfigChanged)
+	}
+	return nil
+}
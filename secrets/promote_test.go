@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/scalescape/dolores"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envFile(t *testing.T, data string) *dolores.EnvFile {
+	t.Helper()
+	ef, err := dolores.ParseEnvFile([]byte(data))
+	require.NoError(t, err)
+	return ef
+}
+
+func TestShouldBuildPromotedFile(t *testing.T) {
+	override := []dolores.Variable{{Key: []byte("LOG_LEVEL"), Value: []byte("warn")}}
+	tests := []struct {
+		name     string
+		current  string
+		noTarget bool
+		cfg      PromoteConfig
+		expected map[string]string
+	}{
+		{
+			name:     "whole config with overrides",
+			current:  "OLD=1\n",
+			cfg:      PromoteConfig{Overrides: override},
+			expected: map[string]string{"API_URL": "https://staging", "TOKEN": "s3cret", "LOG_LEVEL": "warn"},
+		},
+		{
+			name:     "keys merged into existing target",
+			current:  "API_URL=https://prod\nOLD=1\n",
+			cfg:      PromoteConfig{Keys: []string{"TOKEN"}},
+			expected: map[string]string{"API_URL": "https://prod", "OLD": "1", "TOKEN": "s3cret"},
+		},
+		{
+			name:     "keys into new target",
+			noTarget: true,
+			cfg:      PromoteConfig{Keys: []string{"TOKEN"}, Overrides: override},
+			expected: map[string]string{"TOKEN": "s3cret", "LOG_LEVEL": "warn"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := envFile(t, "API_URL=https://staging\nTOKEN=s3cret\nLOG_LEVEL=debug\n")
+			var current *dolores.EnvFile
+			if !tc.noTarget {
+				current = envFile(t, tc.current)
+			}
+
+			promoted, err := promotedFile(src, current, tc.cfg)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, variableValues(variablesOf(promoted)))
+		})
+	}
+}
+
+func TestShouldFailToMergeMissingSourceKey(t *testing.T) {
+	_, err := mergeKeys(envFile(t, "TOKEN=s3cret\n"), nil, []string{"MISSING"})
+
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestShouldClearRotationOnlyOfPromotedKeys(t *testing.T) {
+	src := envFile(t, "API_URL=https://staging\nTOKEN=s3cret\n")
+	cfg := PromoteConfig{
+		Keys:      []string{"TOKEN"},
+		Overrides: []dolores.Variable{{Key: []byte("LOG_LEVEL"), Value: []byte("warn")}},
+	}
+
+	marked := withoutKeys([]string{"TOKEN", "DB_PASSWORD", "LOG_LEVEL", "API_URL"}, promotedKeys(src, cfg))
+
+	assert.Equal(t, []string{"DB_PASSWORD", "API_URL"}, marked)
+}
@@ -0,0 +1,192 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/lib"
+)
+
+var ErrNoConfigSelected = errors.New("pass the configs to rekey with --name or --all")
+
+type RekeyConfig struct {
+	DecryptConfig
+	Names  []string
+	All    bool
+	DryRun bool
+}
+
+// recipientChange compares the recipients of a config with the current
+// ones, a config without an envelope is always out of date.
+type recipientChange struct {
+	added, removed int
+	unknown        bool
+}
+
+func (c recipientChange) upToDate() bool {
+	return !c.unknown && c.added == 0 && c.removed == 0
+}
+
+func (c recipientChange) String() string {
+	if c.unknown {
+		return "no envelope"
+	}
+	return fmt.Sprintf("+%d -%d recipients", c.added, c.removed)
+}
+
+func compareRecipients(env *dolores.Envelope, current map[string]bool) recipientChange {
+	if env == nil {
+		return recipientChange{unknown: true}
+	}
+	var change recipientChange
+	seen := make(map[string]bool, len(env.Recipients))
+	for _, fp := range env.Recipients {
+		seen[fp] = true
+		if !current[fp] {
+			change.removed++
+		}
+	}
+	for fp := range current {
+		if !seen[fp] {
+			change.added++
+		}
+	}
+	return change
+}
+
+// Rekey re-encrypts the configs whose recipients differ from the current
+// keys, so new members can read them and removed ones can't read updates.
+func (sm SecretManager) Rekey(cfg RekeyConfig) error {
+	names, err := sm.rekeyNames(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Passphrase == nil {
+		cfg.Passphrase = dolores.RememberPassphrase(lib.ReadPassphrase)
+	}
+	recps, err := sm.recipients(cfg.Environment)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool, len(recps))
+	for _, r := range recps {
+		current[dolores.Fingerprint(r)] = true
+	}
+	var enc *dolores.Encryptor
+	if !cfg.DryRun {
+		if enc, err = sm.newEncryptor(cfg.Environment, recps); err != nil {
+			return err
+		}
+	}
+	lineFormat := "%-30s %s\n"
+	var errs []error
+	for _, name := range names {
+		status, err := sm.rekey(cfg, name, current, enc)
+		if err != nil {
+			status = "failed: " + err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		fmt.Fprintf(cfg.Output(), lineFormat, name, status)
+	}
+	return errors.Join(errs...)
+}
+
+func (sm SecretManager) rekeyNames(cfg RekeyConfig) ([]string, error) {
+	if !cfg.All {
+		if len(cfg.Names) == 0 {
+			return nil, ErrNoConfigSelected
+		}
+		return cfg.Names, nil
+	}
+	return sm.configNames(cfg.Environment)
+}
+
+func (sm SecretManager) configNames(env string) ([]string, error) {
+	resp, err := sm.client.GetSecretList(client.SecretListConfig{Environment: env})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets: %w", err)
+	}
+	names := make([]string, 0, len(resp))
+	for _, obj := range resp {
+		if !obj.IsKey() && !obj.IsDir() {
+			names = append(names, obj.BaseName())
+		}
+	}
+	return names, nil
+}
+
+// fetchEnvelope fetches the config and reads its envelope, the caller
+// closes the returned closer.
+func (sm SecretManager) fetchEnvelope(environment, name string) (*dolores.Envelope, io.Reader, io.Closer, error) {
+	return sm.fetchRequestEnvelope(client.FetchSecretRequest{Environment: environment, Name: name})
+}
+
+func (sm SecretManager) fetchRequestEnvelope(req client.FetchSecretRequest) (*dolores.Envelope, io.Reader, io.Closer, error) {
+	src, err := sm.client.FetchSecretStream(req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	env, payload, err := dolores.ReadEnvelope(src)
+	if err != nil {
+		src.Close()
+		return nil, nil, nil, err
+	}
+	return env, payload, src, nil
+}
+
+// decryptAll decrypts the payload following the envelope in full, so it's
+// verified before it's used.
+func (sm SecretManager) decryptAll(cfg DecryptConfig, env *dolores.Envelope, payload io.Reader) ([]byte, error) {
+	dec, err := sm.decryptor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if env != nil {
+		payload = io.MultiReader(bytes.NewReader(env.Bytes()), payload)
+	}
+	plain, err := dec.DecryptFrom(payload)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", cfg.Name, err)
+	}
+	return data, nil
+}
+
+// rekey decrypts the whole config before uploading it again, so it's
+// verified and never overwritten with a partial read.
+func (sm SecretManager) rekey(cfg RekeyConfig, name string, current map[string]bool, enc *dolores.Encryptor) (string, error) {
+	env, payload, src, err := sm.fetchEnvelope(cfg.Environment, name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	change := compareRecipients(env, current)
+	if change.upToDate() {
+		return "up to date", nil
+	}
+	if cfg.DryRun {
+		return "out of date, " + change.String(), nil
+	}
+	dcfg := cfg.DecryptConfig
+	dcfg.Name = name
+	data, err := sm.decryptAll(dcfg, env, payload)
+	if err != nil {
+		return "", err
+	}
+	// the values don't change, so the rotation marks still apply
+	marked := *enc
+	if env != nil {
+		marked.NeedsRotation = env.NeedsRotation
+	}
+	if err := sm.upload(&marked, cfg.Environment, name, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return "rekeyed, " + change.String(), nil
+}
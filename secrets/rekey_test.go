@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/rs/zerolog"
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient serves configs and published keys from memory, the methods it
+// doesn't override panic through the nil secClient.
+type fakeClient struct {
+	secClient
+	keys      []string
+	configs   map[string][]byte
+	addKeyErr error
+}
+
+func (f *fakeClient) AddKey(_ string, _ client.UserKeys) error {
+	return f.addKeyErr
+}
+
+func (f *fakeClient) GetOrgPublicKeys(_ string) (client.OrgPublicKeys, error) {
+	recps := make([]client.Recipient, len(f.keys))
+	for i, k := range f.keys {
+		recps[i] = client.Recipient{PublicKey: k}
+	}
+	return client.OrgPublicKeys{Recipients: recps}, nil
+}
+
+func (f *fakeClient) GetKeyring(_ string) ([]byte, error) {
+	return nil, client.ErrMethodUndefined
+}
+
+func (f *fakeClient) FetchSecretStream(req client.FetchSecretRequest) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.configs[req.Name])), nil
+}
+
+func newRecipient(t *testing.T) string {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return id.Recipient().String()
+}
+
+func encryptTo(t *testing.T, keys ...string) []byte {
+	t.Helper()
+	enc, err := dolores.NewEncryptor(keys...)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	require.NoError(t, enc.EncryptTo(buf, strings.NewReader("KEY=value\n")))
+	return buf.Bytes()
+}
+
+func TestShouldCompareRecipients(t *testing.T) {
+	current := map[string]bool{"alice": true, "bob": true}
+	tests := []struct {
+		name     string
+		env      *dolores.Envelope
+		expected recipientChange
+		upToDate bool
+	}{
+		{name: "no envelope", env: nil, expected: recipientChange{unknown: true}},
+		{name: "unchanged", env: &dolores.Envelope{Recipients: []string{"alice", "bob"}}, upToDate: true},
+		{name: "added", env: &dolores.Envelope{Recipients: []string{"alice"}}, expected: recipientChange{added: 1}},
+		{name: "removed", env: &dolores.Envelope{Recipients: []string{"alice", "bob", "carol"}}, expected: recipientChange{removed: 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			change := compareRecipients(tc.env, current)
+
+			assert.Equal(t, tc.expected, change)
+			assert.Equal(t, tc.upToDate, change.upToDate())
+		})
+	}
+}
+
+func TestShouldReportOutOfDateConfigsOnRekeyDryRun(t *testing.T) {
+	t.Setenv(publicKeyEnv, "")
+	alice, bob := newRecipient(t), newRecipient(t)
+	fc := &fakeClient{keys: []string{alice, bob}, configs: map[string][]byte{
+		"current": encryptTo(t, alice, bob),
+		"stale":   encryptTo(t, alice),
+		"legacy":  []byte("age-encryption.org/v1\n"),
+	}}
+	out := new(bytes.Buffer)
+	cfg := RekeyConfig{DecryptConfig: DecryptConfig{Environment: "staging", Out: out}, Names: []string{"current", "stale", "legacy"}, DryRun: true}
+
+	require.NoError(t, NewSecretsManager(zerolog.Nop(), fc).Rekey(cfg))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^current\s+up to date$`, lines[0])
+	assert.Regexp(t, `^stale\s+out of date, \+1 -0 recipients$`, lines[1])
+	assert.Regexp(t, `^legacy\s+out of date, no envelope$`, lines[2])
+}
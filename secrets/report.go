@@ -0,0 +1,266 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+)
+
+// rawFileKey stands for the whole content of a raw config in the report.
+const rawFileKey = "*"
+
+type ReportConfig struct {
+	DecryptConfig
+	UserID string
+	// PreviousKeys are earlier public keys of the user, configs encrypted
+	// to them are exposed too.
+	PreviousKeys []string
+	JSON         bool
+	// Mark flags the exposed keys as needing rotation in the envelope of
+	// each config.
+	Mark bool
+}
+
+// ExposureReport lists the configs and keys a user's key could decrypt.
+type ExposureReport struct {
+	UserID      string `json:"user_id"`
+	Fingerprint string `json:"fingerprint"`
+	// Fingerprints are all the keys of the user matched in the configs.
+	Fingerprints []string           `json:"fingerprints"`
+	Configs      []ExposedConfig    `json:"configs"`
+	Unreadable   []UnreadableConfig `json:"unreadable,omitempty"`
+}
+
+type ExposedConfig struct {
+	Name string   `json:"name"`
+	Keys []string `json:"keys"`
+	// Versions are the versions of the config encrypted to the user.
+	Versions []string `json:"versions"`
+	Raw      bool     `json:"raw,omitempty"`
+	// NoEnvelope is set for configs uploaded without recipient
+	// fingerprints, they're assumed to be exposed.
+	NoEnvelope bool `json:"no_envelope,omitempty"`
+}
+
+// UnreadableConfig is a config, or one of its versions, the report couldn't
+// check, so it may be exposed.
+type UnreadableConfig struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error"`
+}
+
+// ExposureReport finds the versions of the configs encrypted to any of the
+// user's keys and the keys in them, the caller's key is used to read the
+// key names. Configs that can't be read are reported, not failed on.
+func (sm SecretManager) ExposureReport(cfg ReportConfig) (ExposureReport, error) {
+	pub, fingerprints, err := sm.userFingerprints(cfg.Environment, cfg.UserID, cfg.PreviousKeys)
+	if err != nil {
+		return ExposureReport{}, err
+	}
+	report := ExposureReport{
+		UserID: cfg.UserID, Fingerprint: dolores.Fingerprint(pub), Fingerprints: fingerprints,
+		Configs: make([]ExposedConfig, 0),
+	}
+	names, err := sm.configNames(cfg.Environment)
+	if err != nil {
+		return ExposureReport{}, err
+	}
+	for _, name := range names {
+		versions, err := sm.client.ListSecretVersions(client.FetchSecretRequest{Environment: cfg.Environment, Name: name})
+		if err != nil {
+			report.Unreadable = append(report.Unreadable, UnreadableConfig{Name: name, Error: err.Error()})
+			continue
+		}
+		exposed := ExposedConfig{Name: name, Keys: make([]string, 0), Versions: make([]string, 0)}
+		for _, v := range versions {
+			ev, ok, err := sm.exposedVersion(cfg, name, v.Version, fingerprints)
+			if err != nil {
+				report.Unreadable = append(report.Unreadable, UnreadableConfig{Name: name, Version: v.Version, Error: err.Error()})
+				continue
+			}
+			if ok {
+				exposed.merge(v.Version, ev)
+			}
+		}
+		if len(exposed.Versions) > 0 {
+			report.Configs = append(report.Configs, exposed)
+		}
+	}
+	return report, nil
+}
+
+// userFingerprints returns the current public key of the user and the
+// fingerprints of all its known keys.
+func (sm SecretManager) userFingerprints(env, userID string, previous []string) (string, []string, error) {
+	keys, err := sm.client.ListKeys(env)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	var pubs []string
+	for _, uk := range keys {
+		if uk.UserID == userID {
+			pubs = append(pubs, uk.PublicKey)
+		}
+	}
+	if k, err := sm.keyring(env); err == nil {
+		if e, ok := k.Entry(userID); ok {
+			pubs = append(pubs, e.PublicKey)
+		}
+	}
+	if len(pubs) == 0 {
+		return "", nil, fmt.Errorf("no key published for %s: %w", userID, ErrInvalidUser)
+	}
+	fingerprints := make([]string, 0, len(pubs)+len(previous))
+	for _, pub := range append(pubs, previous...) {
+		if fp := dolores.Fingerprint(pub); !contains(fingerprints, fp) {
+			fingerprints = append(fingerprints, fp)
+		}
+	}
+	return pubs[0], fingerprints, nil
+}
+
+func (sm SecretManager) exposedVersion(cfg ReportConfig, name, version string, fingerprints []string) (ExposedConfig, bool, error) {
+	env, payload, src, err := sm.fetchRequestEnvelope(client.FetchSecretRequest{Environment: cfg.Environment, Name: name, Version: version})
+	if err != nil {
+		return ExposedConfig{}, false, err
+	}
+	defer src.Close()
+	exposed := ExposedConfig{Name: name, Keys: make([]string, 0), NoEnvelope: env == nil}
+	if env != nil && !containsAny(env.Recipients, fingerprints) {
+		return ExposedConfig{}, false, nil
+	}
+	dcfg := cfg.DecryptConfig
+	dcfg.Name = name
+	data, err := sm.decryptAll(dcfg, env, payload)
+	if err != nil {
+		return ExposedConfig{}, false, err
+	}
+	header, _, err := dolores.ReadPayloadHeader(bytes.NewReader(data))
+	if err != nil {
+		return ExposedConfig{}, false, err
+	}
+	if header.IsRaw() {
+		exposed.Raw, exposed.Keys = true, []string{rawFileKey}
+		return exposed, true, nil
+	}
+	ef, err := dolores.ParsePayload(data)
+	if err != nil {
+		return ExposedConfig{}, false, err
+	}
+	for _, v := range ef.Variables {
+		exposed.Keys = append(exposed.Keys, string(v.Key))
+	}
+	return exposed, true, nil
+}
+
+// merge adds the exposure of a version to the config.
+func (c *ExposedConfig) merge(version string, ev ExposedConfig) {
+	c.Versions = append(c.Versions, version)
+	c.Raw = c.Raw || ev.Raw
+	c.NoEnvelope = c.NoEnvelope || ev.NoEnvelope
+	for _, k := range ev.Keys {
+		if !contains(c.Keys, k) {
+			c.Keys = append(c.Keys, k)
+		}
+	}
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, w := range wanted {
+		if contains(values, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport prints the report as JSON or as a rotation checklist.
+func (sm SecretManager) WriteReport(cfg ReportConfig, report ExposureReport) error {
+	out := cfg.Output()
+	if cfg.JSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(out, "configs readable by %s (%s):\n", report.UserID, strings.Join(report.Fingerprints, ", "))
+	lineFormat := "%-4s %-30s %-40s %s\n"
+	fmt.Fprintf(out, lineFormat, "", "Config", "Key", "Note")
+	for _, c := range report.Configs {
+		note := "versions: " + strings.Join(c.Versions, ", ")
+		if c.NoEnvelope {
+			note = "no envelope, assumed readable"
+		}
+		for _, k := range c.Keys {
+			if k == rawFileKey {
+				k = "(whole file)"
+			}
+			fmt.Fprintf(out, lineFormat, "[ ]", c.Name, k, note)
+		}
+	}
+	for _, u := range report.Unreadable {
+		name := u.Name
+		if u.Version != "" {
+			name += "@" + u.Version
+		}
+		fmt.Fprintf(out, lineFormat, "[ ]", name, "(unknown)", "unreadable: "+u.Error)
+	}
+	return nil
+}
+
+// MarkRotation records the exposed keys in the envelope of each config,
+// configs without an envelope can't be marked.
+func (sm SecretManager) MarkRotation(cfg ReportConfig, report ExposureReport) error {
+	for _, c := range report.Configs {
+		if c.NoEnvelope {
+			sm.log.Warn().Msgf("can't mark %s, it has no envelope, rekey it first", c.Name)
+			continue
+		}
+		if err := sm.markConfig(cfg.Environment, c); err != nil {
+			return fmt.Errorf("failed to mark %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (sm SecretManager) markConfig(environment string, c ExposedConfig) error {
+	data, err := sm.client.FetchSecrets(client.FetchSecretRequest{Environment: environment, Name: c.Name})
+	if err != nil {
+		return err
+	}
+	env, payload, err := dolores.ReadEnvelope(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		return dolores.ErrInvalidFormat
+	}
+	for _, k := range c.Keys {
+		if !contains(env.NeedsRotation, k) {
+			env.NeedsRotation = append(env.NeedsRotation, k)
+		}
+	}
+	r := io.MultiReader(bytes.NewReader(env.Bytes()), payload)
+	if err := sm.client.UploadSecretStream(client.SecretStream{Environment: environment, Name: c.Name, Data: r}); err != nil {
+		return err
+	}
+	sm.log.Info().Msgf("marked %d keys of %s as needing rotation", len(c.Keys), c.Name)
+	return nil
+}
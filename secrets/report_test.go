@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/rs/zerolog"
+	"github.com/scalescape/dolores/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reportClient serves every version of the configs, keyed by name@version.
+type reportClient struct {
+	fakeClient
+	userKeys []client.UserKeys
+	versions map[string][]client.SecretVersion
+}
+
+func (f *reportClient) ListKeys(_ string) ([]client.UserKeys, error) {
+	return f.userKeys, nil
+}
+
+func (f *reportClient) GetSigners(_ string) ([]client.Signer, error) {
+	return nil, client.ErrMethodUndefined
+}
+
+func (f *reportClient) GetSecretList(_ client.SecretListConfig) ([]client.SecretObject, error) {
+	return []client.SecretObject{{Name: "api"}, {Name: "broken"}, {Name: "worker"}}, nil
+}
+
+func (f *reportClient) ListSecretVersions(req client.FetchSecretRequest) ([]client.SecretVersion, error) {
+	versions, ok := f.versions[req.Name]
+	if !ok {
+		return nil, errors.New("access denied")
+	}
+	return versions, nil
+}
+
+func (f *reportClient) FetchSecretStream(req client.FetchSecretRequest) (io.ReadCloser, error) {
+	data, ok := f.configs[req.Name+"@"+req.Version]
+	if !ok {
+		return nil, client.ErrInvalidVersion
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestShouldReportEveryVersionEncryptedToAnyUserKey(t *testing.T) {
+	caller, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	me := caller.Recipient().String()
+	carol, carolBefore := newRecipient(t), newRecipient(t)
+	cli := &reportClient{
+		fakeClient: fakeClient{configs: map[string][]byte{
+			"api@current":    encryptTo(t, me, carol),
+			"api@v1":         encryptTo(t, me, carolBefore),
+			"worker@current": encryptTo(t, me),
+		}},
+		userKeys: []client.UserKeys{{UserID: "carol", PublicKey: carol}},
+		versions: map[string][]client.SecretVersion{
+			"api":    {{Version: "current", Current: true}, {Version: "v1"}, {Version: "v0"}},
+			"worker": {{Version: "current", Current: true}},
+		},
+	}
+	cfg := ReportConfig{
+		DecryptConfig: DecryptConfig{Environment: "production", Key: caller.String(), AllowUnsigned: true},
+		UserID:        "carol",
+		PreviousKeys:  []string{carolBefore},
+	}
+
+	report, err := NewSecretsManager(zerolog.Nop(), cli).ExposureReport(cfg)
+
+	require.NoError(t, err)
+	require.Len(t, report.Configs, 1)
+	assert.Equal(t, "api", report.Configs[0].Name)
+	assert.Equal(t, []string{"current", "v1"}, report.Configs[0].Versions)
+	assert.Equal(t, []string{"KEY"}, report.Configs[0].Keys)
+	require.Len(t, report.Unreadable, 2)
+	assert.Equal(t, UnreadableConfig{Name: "api", Version: "v0", Error: client.ErrInvalidVersion.Error()}, report.Unreadable[0])
+	assert.Equal(t, "broken", report.Unreadable[1].Name)
+}
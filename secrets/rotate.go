@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/scalescape/dolores"
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/config"
+)
+
+var ErrRotationPending = errors.New("the previous rotation isn't confirmed, run `dolores keys rotate --confirm` first")
+
+type RotateConfig struct {
+	Environment string
+	// Passphrase protects the new identity file when set.
+	Passphrase    []byte
+	AllowUnsigned bool
+	Out           io.Writer
+}
+
+// revive:disable function-length
+
+// RotateKey replaces the identity of the current user and re-encrypts the
+// configs to the new key, decrypting them with the old one. The old
+// identity file is kept as a dated backup until the rotation is confirmed.
+func (sm SecretManager) RotateKey(cfg RotateConfig) error {
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	ec, ok := d.Environments[cfg.Environment]
+	if !ok {
+		return fmt.Errorf("%s: %w", cfg.Environment, ErrInvalidEnvironment)
+	}
+	if ec.BackupKeyFile != "" {
+		return ErrRotationPending
+	}
+	newPath := filepath.Join(config.Dir, cfg.Environment+".key")
+	oldKey, backup, err := backupIdentity(ec.KeyFile, newPath)
+	if err != nil {
+		return err
+	}
+	prev := ec
+	undo := func(err error) error {
+		if rerr := restoreIdentity(d, cfg.Environment, prev, newPath, backup); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+	data, pub, err := dolores.GenerateIdentity(cfg.Passphrase)
+	if err != nil {
+		return undo(err)
+	}
+	if err := os.WriteFile(newPath, data, 0o600); err != nil {
+		return undo(fmt.Errorf("failed to write key file %s: %w", newPath, err))
+	}
+	ec.KeyFile, ec.BackupKeyFile = newPath, backup
+	d.Environments[cfg.Environment] = ec
+	if err := d.SaveToDisk(); err != nil {
+		return undo(fmt.Errorf("error saving dolores config: %w", err))
+	}
+	if err := sm.publishKey(cfg.Environment, ec.UserID, pub); err != nil {
+		return undo(fmt.Errorf("failed to publish new identity, the old one is restored: %w", err))
+	}
+	sm.log.Info().Msgf("generated new identity %s", newPath)
+	rcfg := RekeyConfig{
+		DecryptConfig: DecryptConfig{
+			Environment: cfg.Environment, KeyFile: oldKey,
+			AllowUnsigned: cfg.AllowUnsigned, Out: cfg.Out,
+		},
+		All: true,
+	}
+	if err := sm.Rekey(rcfg); err != nil {
+		return fmt.Errorf("failed to re-encrypt, finish with `dolores rekey --all --key-file %s`: %w", oldKey, err)
+	}
+	if backup == "" {
+		sm.log.Info().Msgf("rotated identity, %s isn't used anymore", oldKey)
+		return nil
+	}
+	sm.log.Info().Msgf("rotated identity, the old one is kept at %s until `dolores keys rotate --confirm`", backup)
+	return nil
+}
+
+// backupIdentity moves the identity at path aside with the date in its
+// name, keys registered from elsewhere like ssh keys are left in place.
+func backupIdentity(current, path string) (string, string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return current, "", nil
+	}
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("2006-01-02T150405"))
+	if err := os.Rename(path, backup); err != nil {
+		return "", "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if current == path {
+		return backup, backup, nil
+	}
+	return current, backup, nil
+}
+
+// restoreIdentity puts the identity backed up by a failed rotation back in
+// place, along with its config, so the rotation can be run again.
+func restoreIdentity(d *config.Dolores, env string, prev config.Environment, path, backup string) error {
+	if backup != "" {
+		if err := os.Rename(backup, path); err != nil {
+			return fmt.Errorf("failed to restore %s from %s: %w", path, backup, err)
+		}
+	} else if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	d.Environments[env] = prev
+	if err := d.SaveToDisk(); err != nil {
+		return fmt.Errorf("error saving dolores config: %w", err)
+	}
+	return nil
+}
+
+// publishKey replaces the published key of the user, retiring the old one,
+// and approves it in the keyring.
+func (sm SecretManager) publishKey(env, userID, pub string) error {
+	uk := client.UserKeys{UserID: userID, PublicKey: pub}
+	if _, key, err := loadSigningKey(env); err == nil {
+		uk.SigningKey = key.Public()
+	}
+	if err := sm.client.AddKey(env, uk); err != nil {
+		return fmt.Errorf("failed to upload public key: %w", err)
+	}
+	err := sm.updateKeyring(env, func(k *dolores.Keyring, me string) error {
+		return sm.addKeys(k, env, me, me, me)
+	})
+	if err != nil && !errors.Is(err, client.ErrMethodUndefined) {
+		return err
+	}
+	return nil
+}
+
+// ConfirmRotation deletes the identity backed up by the last rotation.
+func (sm SecretManager) ConfirmRotation(env string) error {
+	d, err := config.LoadFromDisk()
+	if err != nil {
+		return fmt.Errorf("dolores not initialized yet: %w", err)
+	}
+	ec := d.Environments[env]
+	if ec.BackupKeyFile == "" {
+		sm.log.Info().Msgf("no rotation to confirm")
+		return nil
+	}
+	if err := os.Remove(ec.BackupKeyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", ec.BackupKeyFile, err)
+	}
+	sm.log.Info().Msgf("deleted old identity %s", ec.BackupKeyFile)
+	ec.BackupKeyFile = ""
+	d.Environments[env] = ec
+	return d.SaveToDisk()
+}
@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/scalescape/dolores/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldRestoreIdentityWhenPublishingFails(t *testing.T) {
+	dir := t.TempDir()
+	prevDir, prevFile := config.Dir, config.File
+	config.Dir, config.File = dir, filepath.Join(dir, "dolores.json")
+	t.Cleanup(func() { config.Dir, config.File = prevDir, prevFile })
+	keyFile := filepath.Join(dir, "staging.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("old identity"), 0o600))
+	d := config.Dolores{}
+	d.AddEnvironment("staging", keyFile, "", "alice", config.Metadata{Environment: "staging"})
+	require.NoError(t, d.SaveToDisk())
+	publishErr := errors.New("unavailable")
+
+	err := NewSecretsManager(zerolog.Nop(), &fakeClient{addKeyErr: publishErr}).RotateKey(RotateConfig{Environment: "staging"})
+
+	require.ErrorIs(t, err, publishErr)
+	data, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "old identity", string(data))
+	saved, err := config.LoadFromDisk()
+	require.NoError(t, err)
+	assert.Equal(t, d.Environments["staging"], saved.Environments["staging"])
+	backups, err := filepath.Glob(keyFile + ".*.bak")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/scalescape/dolores"
+)
+
+var (
+	ErrKeyNotFound   = errors.New("key not found in config")
+	ErrConfigChanged = errors.New("config was changed while it was updated, run the command again")
+)
+
+type SetConfig struct {
+	DecryptConfig
+	// Values are assigned in order, a key already set keeps its position.
+	Values []dolores.Variable
+	// Unset removes the keys, each has to be set.
+	Unset []string
+}
+
+type GetConfig struct {
+	DecryptConfig
+	Key string
+}
+
+// SetVariables updates the keys of a dotenv config without an editor. The
+// config is decrypted in full and uploaded once with all the changes, and
+// only if nobody uploaded it in the meantime.
+func (sm SecretManager) SetVariables(cfg SetConfig) error {
+	if err := cfg.Valid(); err != nil {
+		return fmt.Errorf("invalid config: %w: %w", ErrInvalidDecryptConfig, err)
+	}
+	ef, env, err := sm.loadWithEnvelope(cfg.DecryptConfig)
+	if err != nil {
+		return err
+	}
+	if err := applyChanges(ef, cfg); err != nil {
+		return fmt.Errorf("%s: %w", cfg.Name, err)
+	}
+	enc, err := sm.encryptor(cfg.Environment)
+	if err != nil {
+		return err
+	}
+	if env != nil {
+		// keys given a new value are rotated
+		enc.NeedsRotation = withoutKeys(env.NeedsRotation, cfg.Values)
+	}
+	if err := sm.unchanged(cfg.DecryptConfig, env); err != nil {
+		return err
+	}
+	return sm.upload(enc, cfg.Environment, cfg.Name, bytes.NewReader(ef.Payload()))
+}
+
+// loadWithEnvelope decrypts the config in full, keeping its envelope to
+// check it's unchanged before it's replaced.
+func (sm SecretManager) loadWithEnvelope(cfg DecryptConfig) (*dolores.EnvFile, *dolores.Envelope, error) {
+	env, payload, src, err := sm.fetchEnvelope(cfg.Environment, cfg.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+	data, err := sm.decryptAll(cfg, env, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	ef, err := dolores.ParsePayload(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config %s: %w", cfg.Name, err)
+	}
+	return ef, env, nil
+}
+
+func applyChanges(ef *dolores.EnvFile, cfg SetConfig) error {
+	for _, v := range cfg.Values {
+		if err := ef.Set(string(v.Key), v.Value); err != nil {
+			return err
+		}
+	}
+	for _, key := range cfg.Unset {
+		removed, err := ef.Unset(key)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s: %w", key, ErrKeyNotFound)
+		}
+	}
+	return nil
+}
+
+func withoutKeys(keys []string, vars []dolores.Variable) []string {
+	set := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		set[string(v.Key)] = true
+	}
+	res := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if !set[k] {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// unchanged checks the remote config is still the one that was modified,
+// configs without an envelope can't be compared.
+func (sm SecretManager) unchanged(cfg DecryptConfig, before *dolores.Envelope) error {
+	if before == nil {
+		return nil
+	}
+	env, _, src, err := sm.fetchEnvelope(cfg.Environment, cfg.Name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if env == nil || env.ContentHash != before.ContentHash {
+		return fmt.Errorf("%s: %w", cfg.Name, ErrConfigChanged)
+	}
+	return nil
+}
+
+// GetVariable writes the value of a single key, with a trailing newline.
+func (sm SecretManager) GetVariable(cfg GetConfig) error {
+	ef, err := sm.Load(cfg.DecryptConfig)
+	if err != nil {
+		return err
+	}
+	if ef.Format == dolores.FormatRaw {
+		return fmt.Errorf("%s: %w", cfg.Name, dolores.ErrRawConfig)
+	}
+	value, ok := ef.Get(cfg.Key)
+	if !ok {
+		return fmt.Errorf("%s: %w", cfg.Key, ErrKeyNotFound)
+	}
+	if _, err := fmt.Fprintf(cfg.Output(), "%s\n", value); err != nil {
+		return err
+	}
+	return nil
+}
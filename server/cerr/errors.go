@@ -9,4 +9,8 @@ var (
 	ErrInvalidOrgID         = errors.New("invalid org id")
 	ErrInvalidSecretRequest = errors.New("invalid secrets request")
 	ErrNoSecretFound        = errors.New("no secrets found")
+	ErrNoUserFound          = errors.New("no user found")
+	ErrInvalidKeysRequest   = errors.New("invalid keys request")
+	ErrEnvironmentExists    = errors.New("environment already exists")
+	ErrSecretExists         = errors.New("secret already exists")
 )
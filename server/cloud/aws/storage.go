@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -113,6 +114,112 @@ func (s StorageClient) ReadObject(ctx context.Context, bucketName, fileName stri
 	return data, nil
 }
 
+func (s StorageClient) ExistsObject(ctx context.Context, bucketName, fileName string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		var notFoundType *types.NotFound
+		if errors.As(err, &notFoundType) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check object: %w", err)
+	}
+	return true, nil
+}
+
+func (s StorageClient) CopyObject(ctx context.Context, bucketName, src, dst string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucketName),
+		CopySource: aws.String(bucketName + "/" + url.PathEscape(src)),
+		Key:        aws.String(dst),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) DeleteObject(ctx context.Context, bucketName, fileName string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) VersioningEnabled(ctx context.Context, bucketName string) (bool, error) {
+	resp, err := s.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return false, fmt.Errorf("failed to get bucket versioning: %w", err)
+	}
+	return resp.Status == types.BucketVersioningStatusEnabled, nil
+}
+
+// ListObjectVersions returns the versions of the object, newest first. The
+// listing is paged by key and version markers.
+func (s StorageClient) ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cld.ObjectVersion, error) {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(fileName),
+	}
+	versions := make([]cld.ObjectVersion, 0)
+	for {
+		resp, err := s.client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list object versions: %w", err)
+		}
+		for _, v := range resp.Versions {
+			if aws.ToString(v.Key) != fileName {
+				continue
+			}
+			versions = append(versions, cld.ObjectVersion{
+				Name: fileName, Version: aws.ToString(v.VersionId),
+				UpdatedAt: aws.ToTime(v.LastModified), Latest: v.IsLatest,
+			})
+		}
+		if !resp.IsTruncated {
+			return versions, nil
+		}
+		input.KeyMarker, input.VersionIdMarker = resp.NextKeyMarker, resp.NextVersionIdMarker
+	}
+}
+
+// DeleteObjectVersion removes the version for good, deleting the object
+// only adds a delete marker in versioned buckets.
+func (s StorageClient) DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(fileName),
+		VersionId: aws.String(version),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object version %s: %w", version, err)
+	}
+	return nil
+}
+
+func (s StorageClient) ReadObjectVersion(ctx context.Context, bucketName, fileName, version string) ([]byte, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(fileName),
+		VersionId: aws.String(version),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object version %s: %w", version, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body : %w", err)
+	}
+	return data, nil
+}
+
 func NewStorageClient(ctx context.Context, acfg Config) (StorageClient, error) {
 	cp := credentials.NewStaticCredentialsProvider(acfg.AccessKeyID, acfg.SecretAccessKey, acfg.Token)
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(acfg.Region), config.WithCredentialsProvider(cp))
@@ -5,15 +5,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/config"
 	"github.com/scalescape/dolores/server/cerr"
 )
 
 const (
-	StagingEnv    Environment = "staging"
-	ProductionEnv Environment = "production"
-	GlobalEnv     Environment = "global"
-	DemoEnv       Environment = "demo"
-	EnvID         contextKey  = "env"
+	GlobalEnv Environment = "global"
+	EnvID     contextKey  = "env"
 )
 
 type (
@@ -23,8 +21,10 @@ type (
 
 func (e Environment) String() string { return string(e) }
 
+// Valid checks the name of the environment, whether the org has it is
+// checked against its projects.
 func (e Environment) Valid() error {
-	if e != StagingEnv && e != ProductionEnv && e != DemoEnv {
+	if config.ValidEnvironmentName(string(e)) != nil {
 		return cerr.ErrInvalidEnvironment
 	}
 	return nil
@@ -35,30 +35,19 @@ func ParsePathEnv(r *http.Request) (Environment, error) {
 	if !ok || environment == "" {
 		return "", cerr.ErrInvalidEnvironment
 	}
-	switch environment {
-	case "staging":
-		return StagingEnv, nil
-	case "demo":
-		return DemoEnv, nil
-	case "production":
-		return ProductionEnv, nil
-	default:
+	env := Environment(environment)
+	if err := env.Valid(); err != nil {
 		log.Error().Msgf("failed to parse env from path: %s", environment)
-		return "", cerr.ErrInvalidEnvironment
+		return "", err
 	}
+	return env, nil
 }
 
 func ParseQueryEnv(r *http.Request) (Environment, error) {
-	env := r.URL.Query().Get(string(EnvID))
-	switch env {
-	case "staging":
-		return StagingEnv, nil
-	case "production":
-		return ProductionEnv, nil
-	case "demo":
-		return DemoEnv, nil
-	default:
+	env := Environment(r.URL.Query().Get(string(EnvID)))
+	if err := env.Valid(); err != nil {
 		log.Error().Msgf("failed to parse env from query: %s", env)
-		return "", cerr.ErrInvalidEnvironment
+		return "", err
 	}
+	return env, nil
 }
@@ -8,3 +8,10 @@ type Object struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type ObjectVersion struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Latest    bool      `json:"latest"`
+}
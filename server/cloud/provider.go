@@ -12,6 +12,13 @@ type StorageClient interface {
 	WriteToObject(ctx context.Context, bucket string, file string, data []byte) error
 	ReadObject(ctx context.Context, bucketName, fileName string) ([]byte, error)
 	ListObject(ctx context.Context, bucketName, fileName string) ([]cld.Object, error)
+	ExistsObject(ctx context.Context, bucketName, fileName string) (bool, error)
+	CopyObject(ctx context.Context, bucketName, src, dst string) error
+	DeleteObject(ctx context.Context, bucketName, fileName string) error
+	VersioningEnabled(ctx context.Context, bucketName string) (bool, error)
+	ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cld.ObjectVersion, error)
+	ReadObjectVersion(ctx context.Context, bucketName, fileName, version string) ([]byte, error)
+	DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error
 }
 
 type Option func(*Config)
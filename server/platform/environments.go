@@ -0,0 +1,79 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
+	"github.com/scalescape/dolores/server/lib"
+	"github.com/scalescape/dolores/server/org"
+)
+
+type environmentRequest struct {
+	Environment string `json:"environment"`
+	From        string `json:"from"`
+}
+
+func (r environmentRequest) valid() error {
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
+	}
+	return cld.Environment(r.From).Valid()
+}
+
+type environmentsResponse struct {
+	Environments []string `json:"environments"`
+}
+
+func ListEnvironments(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oid, ok := r.Context().Value(org.IDKey).(string)
+		if !ok || oid == "" {
+			lib.WriteError(w, http.StatusBadRequest, cerr.ErrInvalidOrgID, "invalid org ID")
+			return
+		}
+		envs, err := svc.ListEnvironments(r.Context(), oid)
+		if err != nil {
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to list environments")
+			return
+		}
+		if err := json.NewEncoder(w).Encode(environmentsResponse{Environments: envs}); err != nil {
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to encode result")
+			return
+		}
+	}
+}
+
+func CreateEnvironment(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oid, ok := r.Context().Value(org.IDKey).(string)
+		if !ok || oid == "" {
+			lib.WriteError(w, http.StatusBadRequest, cerr.ErrInvalidOrgID, "invalid org ID")
+			return
+		}
+		var req environmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "failed to decode request")
+			return
+		}
+		if err := req.valid(); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		err := svc.CreateEnvironment(r.Context(), oid, req.From, req.Environment)
+		switch {
+		case errors.Is(err, cerr.ErrEnvironmentExists):
+			lib.WriteError(w, http.StatusConflict, err, "failed to create environment")
+			return
+		case errors.Is(err, cerr.ErrInvalidEnvironment):
+			lib.WriteError(w, http.StatusBadRequest, err, "failed to create environment")
+			return
+		case err != nil:
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to create environment")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+}
@@ -2,9 +2,13 @@ package platform
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/server/cerr"
 )
 
 type Service struct {
@@ -17,6 +21,25 @@ func (s Service) FetchProject(ctx context.Context, oid string, env string) (Proj
 	return s.fetchProject(ctx, oid, env)
 }
 
+// CreateEnvironment adds the environment to the org, set up like the from
+// environment.
+func (s Service) CreateEnvironment(ctx context.Context, oid, from, env string) error {
+	_, err := s.fetchProject(ctx, oid, env)
+	if err == nil {
+		return fmt.Errorf("%s: %w", env, cerr.ErrEnvironmentExists)
+	} else if !errors.Is(err, cerr.ErrNoProjectFound) {
+		return err
+	}
+	if err := s.copyProject(ctx, oid, from, env, uuid.New().String()); err != nil {
+		if errors.Is(err, cerr.ErrNoProjectFound) {
+			return fmt.Errorf("%s: %w", from, cerr.ErrInvalidEnvironment)
+		}
+		return err
+	}
+	s.log.Info().Msgf("created environment %s from %s for org %s", env, from, oid)
+	return nil
+}
+
 func NewService(defZone string, st Store) Service {
 	logger := log.Output(zerolog.NewConsoleWriter()).With().
 		Str("service", "platform").
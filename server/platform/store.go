@@ -68,6 +68,51 @@ func (s Store) fetchProject(ctx context.Context, oid string, env string) (Projec
 	return res, nil
 }
 
+func (s Store) ListEnvironments(ctx context.Context, oid string) ([]string, error) {
+	query := s.db.Rebind(`SELECT environment from projects WHERE org_id = ? ORDER BY environment`)
+	var envs []string
+	if err := s.db.SelectContext(ctx, &envs, query, oid); err != nil {
+		return nil, fmt.Errorf("error fetching environments of org %s: %w", oid, err)
+	}
+	return envs, nil
+}
+
+// ListProjects returns the projects of every org with their credentials.
+func (s Store) ListProjects(ctx context.Context) ([]Project, error) {
+	query := fmt.Sprintf(`SELECT id,
+                            org_id,
+                            environment,
+                            pgp_sym_decrypt(credentials::bytea, '%s') as credentials,
+                            platform,
+                            region,
+                            bucket from projects
+                        ORDER BY org_id, environment`, s.SaltKey)
+	var res []Project
+	if err := s.db.SelectContext(ctx, &res, query); err != nil {
+		return nil, fmt.Errorf("error fetching projects: %w", err)
+	}
+	return res, nil
+}
+
+// copyProject creates the project of env with the platform and credentials
+// of the from environment, the credentials aren't decrypted.
+func (s Store) copyProject(ctx context.Context, oid, from, env, id string) error {
+	query := `INSERT into projects
+        (id, org_id, platform, credentials, name, environment, bucket, dns_zone, subdomain, region)
+        SELECT ?, org_id, platform, credentials, ?, ?, bucket, dns_zone, subdomain, region from projects
+        WHERE org_id = ?
+        AND environment = ?`
+	query = s.db.Rebind(query)
+	res, err := s.db.ExecContext(ctx, query, id, env, env, oid, from)
+	if err != nil {
+		return fmt.Errorf("error creating project %s: %w", env, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return cerr.ErrNoProjectFound
+	}
+	return nil
+}
+
 func NewStore(db *sqlx.DB, key string) Store {
 	return Store{db, key}
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// schema is applied on every start, each statement must be idempotent.
+var schema = []string{
+	`ALTER TABLE user_keys ADD COLUMN IF NOT EXISTS signing_key text`,
+	// keys used to be appended, keep the latest key of a user per environment
+	// so the upsert can rely on the unique index
+	`DELETE FROM user_keys a USING user_keys b
+        WHERE a.user_id = b.user_id and a.environment = b.environment and
+        (a.created_at < b.created_at or (a.created_at = b.created_at and a.ctid < b.ctid))`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS user_keys_user_id_environment_idx ON user_keys (user_id, environment)`,
+}
+
+func migrateSchema(ctx context.Context, db *sqlx.DB) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting schema migration: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+	for _, stmt := range schema {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("error migrating schema: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing schema migration: %w", err)
+	}
+	return nil
+}
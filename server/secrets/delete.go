@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
+	"github.com/scalescape/dolores/server/lib"
+	"github.com/scalescape/dolores/server/org"
+)
+
+type deleteRequest struct {
+	Environment string `json:"environment"`
+	Name        string `json:"name"`
+	orgID       string `json:"-"`
+}
+
+func (r deleteRequest) Valid() error {
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
+	}
+	if r.Name == "" {
+		return cerr.ErrInvalidSecretRequest
+	}
+	return nil
+}
+
+func Delete(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := deleteRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		var ok bool
+		req.orgID, ok = r.Context().Value(org.IDKey).(string)
+		if !ok || req.orgID == "" {
+			lib.WriteError(w, http.StatusBadRequest, cerr.ErrInvalidOrgID, "invalid org ID")
+			return
+		}
+		if err := req.Valid(); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		err := svc.DeleteSecret(r.Context(), req)
+		if errors.Is(err, cerr.ErrNoSecretFound) {
+			lib.WriteError(w, http.StatusNotFound, err, "failed to delete secret")
+			return
+		}
+		if err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to delete secret")
+			return
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/scalescape/dolores/server/cerr"
 	"github.com/scalescape/dolores/server/cloud/cld"
@@ -14,6 +15,7 @@ import (
 type fetchRequest struct {
 	Environment cld.Environment `json:"environment"`
 	Name        string          `json:"name"`
+	Version     string          `json:"version"`
 	orgID       string          `json:"-"`
 }
 
@@ -21,7 +23,7 @@ func (r fetchRequest) Valid() error {
 	if err := r.Environment.Valid(); err != nil {
 		return err
 	}
-	if r.Name == "" {
+	if r.Name == "" || strings.Contains(r.Version, "/") {
 		return cerr.ErrInvalidSecretRequest
 	}
 	return nil
@@ -55,7 +57,7 @@ func Fetch(svc Service) http.HandlerFunc {
 			return
 		}
 		if err != nil {
-			lib.WriteError(w, http.StatusInternalServerError, err, "failed to fetch secrets")
+			lib.WriteError(w, errStatus(err), err, "failed to fetch secrets")
 			return
 		}
 		resp := fetchResponse{Data: data}
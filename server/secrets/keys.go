@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
+	"github.com/scalescape/dolores/server/lib"
+)
+
+type keysRequest struct {
+	Environment string `json:"environment"`
+	UserID      string `json:"user_id"`
+	PublicKey   string `json:"public_key"`
+	SigningKey  string `json:"signing_key"`
+}
+
+func (r keysRequest) valid() error {
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r keysRequest) validUser() error {
+	if err := r.valid(); err != nil {
+		return err
+	}
+	if r.UserID == "" {
+		return cerr.ErrInvalidKeysRequest
+	}
+	return nil
+}
+
+type keysResponse struct {
+	Keys []UserKey `json:"keys"`
+}
+
+func decodeKeysRequest(w http.ResponseWriter, r *http.Request, valid func(keysRequest) error) (keysRequest, bool) {
+	var req keysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		lib.WriteError(w, http.StatusBadRequest, err, "failed to decode request")
+		return keysRequest{}, false
+	}
+	if err := valid(req); err != nil {
+		lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+		return keysRequest{}, false
+	}
+	return req, true
+}
+
+func ListKeys(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeKeysRequest(w, r, keysRequest.valid)
+		if !ok {
+			return
+		}
+		data, err := svc.ListKeys(r.Context(), req.Environment)
+		if err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to list keys")
+			return
+		}
+		if err := json.NewEncoder(w).Encode(keysResponse{Keys: data}); err != nil {
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to encode result")
+			return
+		}
+	}
+}
+
+func AddKey(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeKeysRequest(w, r, func(req keysRequest) error {
+			if req.PublicKey == "" {
+				return cerr.ErrInvalidKeysRequest
+			}
+			return req.validUser()
+		})
+		if !ok {
+			return
+		}
+		if err := svc.AddKey(r.Context(), req); err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to add key")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func RemoveKey(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeKeysRequest(w, r, keysRequest.validUser)
+		if !ok {
+			return
+		}
+		if err := svc.RemoveKey(r.Context(), req); err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to remove key")
+			return
+		}
+	}
+}
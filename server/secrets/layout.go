@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/server/platform"
+)
+
+// MigrateLayout moves the secrets stored before the paths had the
+// environment, with their history, under the environment of their project
+// and records the new location. A bucket shared by several projects can't
+// tell whose a secret is, so only the secrets recorded for the project are
+// moved there. Failed projects keep being read from the legacy paths.
+func (s Service) MigrateLayout(ctx context.Context) error {
+	projects, err := s.proj.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	buckets := make(map[string]int)
+	for _, proj := range projects {
+		buckets[proj.Bucket]++
+	}
+	for _, proj := range projects {
+		if err := s.migrateProject(ctx, proj, buckets[proj.Bucket] > 1); err != nil {
+			log.Error().Msgf("failed to migrate secrets of %s/%s: %v", proj.OrgID, proj.Environment, err)
+		}
+	}
+	return nil
+}
+
+func (s Service) migrateProject(ctx context.Context, proj platform.Project, shared bool) error {
+	sc, err := s.getStorageClient(ctx, proj)
+	if err != nil {
+		return err
+	}
+	objs, err := sc.ListObject(ctx, proj.Bucket, legacySecretsDir+"/")
+	if err != nil {
+		return err
+	}
+	var recorded []string
+	if shared {
+		if recorded, err = s.Store.legacySecrets(ctx, proj.ID); err != nil {
+			return err
+		}
+	}
+	for _, obj := range objs {
+		name := strings.TrimPrefix(obj.Name, legacySecretsDir+"/")
+		if name == "" || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".key") {
+			continue
+		}
+		if shared && !contains(recorded, name) {
+			continue
+		}
+		dst := secretOf(proj.Environment, name)
+		exists, err := sc.ExistsObject(ctx, proj.Bucket, dst.location)
+		if err != nil {
+			return err
+		}
+		if exists {
+			// uploaded since, the legacy copy is stale
+			err = deleteAllVersions(ctx, sc, proj.Bucket, legacySecret(name))
+		} else {
+			err = moveSecret(ctx, sc, proj.Bucket, legacySecret(name), dst)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to move %s: %w", obj.Name, err)
+		}
+		if err := s.Store.updateLocation(ctx, proj.ID, name, dst.location); err != nil {
+			return err
+		}
+		log.Info().Msgf("moved %s to %s", obj.Name, path.Join(proj.Bucket, dst.location))
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -45,7 +45,7 @@ func List(svc Service) http.HandlerFunc {
 		}
 		data, err := svc.ListSecret(ctx, req)
 		if err != nil {
-			lib.WriteError(w, http.StatusInternalServerError, err, "failed to list secrets: %s", req.environment)
+			lib.WriteError(w, errStatus(err), err, "failed to list secrets: %s", req.environment)
 			return
 		}
 		if len(data) == 0 {
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
 	"github.com/scalescape/dolores/server/lib"
 )
 
@@ -13,8 +13,8 @@ type recipientsRequest struct {
 }
 
 func (r recipientsRequest) valid() error {
-	if r.Environment != "staging" && r.Environment != "production" {
-		return cerr.ErrInvalidEnvironment
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
 	}
 	return nil
 }
@@ -36,7 +36,7 @@ func ListRecipients(svc Service) http.HandlerFunc {
 		}
 		data, err := svc.ListRecipients(r.Context(), req.Environment)
 		if err != nil {
-			lib.WriteError(w, http.StatusInternalServerError, err, "failed to list recipients")
+			lib.WriteError(w, errStatus(err), err, "failed to list recipients")
 			return
 		}
 		resp := recipientsResponse{Recipients: data}
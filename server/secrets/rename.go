@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
+	"github.com/scalescape/dolores/server/lib"
+	"github.com/scalescape/dolores/server/org"
+)
+
+type renameRequest struct {
+	Environment string `json:"environment"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	orgID       string `json:"-"`
+}
+
+func (r renameRequest) Valid() error {
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
+	}
+	if r.From == "" || r.To == "" || r.From == r.To {
+		return cerr.ErrInvalidSecretRequest
+	}
+	return nil
+}
+
+func Rename(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := renameRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		var ok bool
+		req.orgID, ok = r.Context().Value(org.IDKey).(string)
+		if !ok || req.orgID == "" {
+			lib.WriteError(w, http.StatusBadRequest, cerr.ErrInvalidOrgID, "invalid org ID")
+			return
+		}
+		if err := req.Valid(); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		err := svc.RenameSecret(r.Context(), req)
+		switch {
+		case errors.Is(err, cerr.ErrNoSecretFound):
+			lib.WriteError(w, http.StatusNotFound, err, "failed to rename secret")
+			return
+		case errors.Is(err, cerr.ErrSecretExists):
+			lib.WriteError(w, http.StatusConflict, err, "failed to rename secret")
+			return
+		case err != nil:
+			lib.WriteError(w, errStatus(err), err, "failed to rename secret")
+			return
+		}
+	}
+}
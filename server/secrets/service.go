@@ -2,8 +2,11 @@ package secrets
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 
 type projFetcher interface {
 	FetchProject(ctx context.Context, oid string, env string) (platform.Project, error)
+	ListProjects(ctx context.Context) ([]platform.Project, error)
 }
 type Service struct {
 	proj projFetcher
@@ -39,11 +43,40 @@ func (s Service) getStorageClient(ctx context.Context, proj platform.Project) (c
 	return sc, nil
 }
 
+// project returns the project of the environment, an environment the org
+// hasn't created is invalid.
+func (s Service) project(ctx context.Context, oid, env string) (platform.Project, error) {
+	proj, err := s.proj.FetchProject(ctx, oid, env)
+	if errors.Is(err, cerr.ErrNoProjectFound) {
+		return platform.Project{}, fmt.Errorf("%s: %w", env, cerr.ErrInvalidEnvironment)
+	} else if err != nil {
+		return platform.Project{}, fmt.Errorf("failed to fetch project: %w", err)
+	}
+	return proj, nil
+}
+
+func (s Service) validEnvironment(ctx context.Context, oid, env string) error {
+	_, err := s.project(ctx, oid, env)
+	return err
+}
+
+// errStatus is the status of a failed request, unknown environments are
+// the client's fault.
+func errStatus(err error) int {
+	if errors.Is(err, cerr.ErrInvalidEnvironment) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (s Service) ListRecipients(ctx context.Context, env string) ([]Recipient, error) {
 	oid, ok := ctx.Value(org.IDKey).(string)
 	if !ok || oid == "" {
 		return nil, fmt.Errorf("empty org id: %w", cerr.ErrInvalidOrg)
 	}
+	if err := s.validEnvironment(ctx, oid, env); err != nil {
+		return nil, err
+	}
 	log.Trace().Msgf("fetching user public keys, env: %s org_id: %s", env, oid)
 	result, err := s.Store.ListUsersPublicKeys(ctx, env, oid)
 	if err != nil {
@@ -56,25 +89,112 @@ func (s Service) ListRecipients(ctx context.Context, env string) ([]Recipient, e
 	return recps, nil
 }
 
+type Signer struct {
+	UserID    string `json:"user_id"`
+	PublicKey string `json:"public_key"`
+}
+
+func (s Service) ListSigners(ctx context.Context, env string) ([]Signer, error) {
+	oid, ok := ctx.Value(org.IDKey).(string)
+	if !ok || oid == "" {
+		return nil, fmt.Errorf("empty org id: %w", cerr.ErrInvalidOrg)
+	}
+	if err := s.validEnvironment(ctx, oid, env); err != nil {
+		return nil, err
+	}
+	log.Trace().Msgf("fetching user signing keys, env: %s org_id: %s", env, oid)
+	result, err := s.Store.ListUsersSigningKeys(ctx, env, oid)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]Signer, len(result))
+	for i, uk := range result {
+		signers[i] = Signer{UserID: uk.UserID, PublicKey: uk.SigningKey.String}
+	}
+	return signers, nil
+}
+
+type UserKey struct {
+	UserID     string    `json:"user_id"`
+	PublicKey  string    `json:"public_key"`
+	SigningKey string    `json:"signing_key,omitempty"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+func (s Service) ListKeys(ctx context.Context, env string) ([]UserKey, error) {
+	oid, ok := ctx.Value(org.IDKey).(string)
+	if !ok || oid == "" {
+		return nil, fmt.Errorf("empty org id: %w", cerr.ErrInvalidOrg)
+	}
+	if err := s.validEnvironment(ctx, oid, env); err != nil {
+		return nil, err
+	}
+	result, err := s.Store.ListUsersKeys(ctx, env, oid)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]UserKey, len(result))
+	for i, uk := range result {
+		keys[i] = UserKey{UserID: uk.UserID, PublicKey: uk.PublicKey, SigningKey: uk.SigningKey.String, AddedAt: uk.CreatedAt}
+	}
+	return keys, nil
+}
+
+func (s Service) AddKey(ctx context.Context, req keysRequest) error {
+	oid, ok := ctx.Value(org.IDKey).(string)
+	if !ok || oid == "" {
+		return fmt.Errorf("empty org id: %w", cerr.ErrInvalidOrg)
+	}
+	if err := s.validEnvironment(ctx, oid, req.Environment); err != nil {
+		return err
+	}
+	key := Key{UserID: req.UserID, Environment: req.Environment, PublicKey: req.PublicKey}
+	if req.SigningKey != "" {
+		key.SigningKey = sql.NullString{String: req.SigningKey, Valid: true}
+	}
+	log.Trace().Msgf("saving key of %s, env: %s org_id: %s", req.UserID, req.Environment, oid)
+	return s.Store.SaveUserKey(ctx, oid, key)
+}
+
+func (s Service) RemoveKey(ctx context.Context, req keysRequest) error {
+	oid, ok := ctx.Value(org.IDKey).(string)
+	if !ok || oid == "" {
+		return fmt.Errorf("empty org id: %w", cerr.ErrInvalidOrg)
+	}
+	if err := s.validEnvironment(ctx, oid, req.Environment); err != nil {
+		return err
+	}
+	log.Trace().Msgf("deleting key of %s, env: %s org_id: %s", req.UserID, req.Environment, oid)
+	return s.Store.DeleteUserKey(ctx, req.Environment, req.UserID, oid)
+}
+
+// ListSecret lists the secrets of the environment, with the ones at their
+// legacy path until the layout is migrated.
 func (s Service) ListSecret(ctx context.Context, req listRequest) ([]Secret, error) {
 	var secs []Secret
-	proj, err := s.proj.FetchProject(ctx, req.orgID, string(req.environment))
+	proj, err := s.project(ctx, req.orgID, string(req.environment))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch project: %w", err)
+		return nil, err
 	}
 	log.Trace().Msgf("listing objects for org: %s, project: %s bucket: %s", req.orgID, proj.ID, proj.Bucket)
 	sc, err := s.getStorageClient(ctx, proj)
 	if err != nil {
 		return nil, err
 	}
-	objs, err := sc.ListObject(ctx, proj.Bucket, "secrets")
-	if err != nil {
-		return nil, err
-	}
-	for _, obj := range objs {
-		if !strings.HasSuffix(obj.Name, ".key") && !strings.HasSuffix(obj.Name, "/") {
+	seen := make(map[string]bool)
+	for _, root := range []string{string(req.environment) + "/", ""} {
+		objs, err := sc.ListObject(ctx, proj.Bucket, root+legacySecretsDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			name := strings.TrimPrefix(obj.Name, root)
+			if strings.HasSuffix(obj.Name, ".key") || strings.HasSuffix(obj.Name, "/") || seen[name] {
+				continue
+			}
+			seen[name] = true
 			secs = append(secs, Secret{
-				Name: obj.Name, CreatedAt: obj.CreatedAt,
+				Name: name, CreatedAt: obj.CreatedAt,
 				UpdatedAt: obj.UpdatedAt, Location: fmt.Sprintf("%s/%s", obj.Bucket, obj.Name),
 			})
 		}
@@ -82,25 +202,39 @@ func (s Service) ListSecret(ctx context.Context, req listRequest) ([]Secret, err
 	return secs, nil
 }
 
+// UploadSecret writes the secret under its environment, a secret still at
+// its legacy path is moved there first so its history is kept.
 func (s Service) UploadSecret(ctx context.Context, req uploadRequest) error {
 	oid, ok := ctx.Value(org.IDKey).(string)
 	if !ok || oid == "" {
 		return cerr.ErrInvalidOrgID
 	}
-	proj, err := s.proj.FetchProject(ctx, oid, req.Environment)
+	proj, err := s.project(ctx, oid, req.Environment)
 	if err != nil {
-		return fmt.Errorf("failed to fetch project: %w", err)
+		return err
 	}
 	sc, err := s.getStorageClient(ctx, proj)
 	if err != nil {
 		return err
 	}
-	name := fmt.Sprintf("secrets/%s", req.Name)
-	err = sc.WriteToObject(ctx, proj.Bucket, name, req.decodedData)
+	obj := secretOf(req.Environment, req.Name)
+	current, err := locate(ctx, sc, proj.Bucket, req.Environment, req.Name)
+	if err != nil && !errors.Is(err, cerr.ErrNoSecretFound) {
+		return err
+	}
+	if err == nil && current != obj {
+		if err := moveSecret(ctx, sc, proj.Bucket, current, obj); err != nil {
+			return fmt.Errorf("failed to move %s: %w", current.location, err)
+		}
+	}
+	if err := keepHistory(ctx, sc, proj.Bucket, obj); err != nil {
+		return err
+	}
+	err = sc.WriteToObject(ctx, proj.Bucket, obj.location, req.decodedData)
 	if err != nil {
 		return fmt.Errorf("failed to write to gcs: %w", err)
 	}
-	sec := Secret{ID: uuid.New().String(), Name: req.Name, ProjectID: proj.ID, Location: name, CreatedAt: time.Now().UTC()}
+	sec := Secret{ID: uuid.New().String(), Name: req.Name, ProjectID: proj.ID, Location: obj.location, CreatedAt: time.Now().UTC()}
 	if err := s.Store.SaveSecret(ctx, sec); err != nil {
 		return err
 	}
@@ -108,9 +242,9 @@ func (s Service) UploadSecret(ctx context.Context, req uploadRequest) error {
 }
 
 func (s Service) FetchSecret(ctx context.Context, req fetchRequest) (string, error) {
-	proj, err := s.proj.FetchProject(ctx, req.orgID, string(req.Environment))
+	proj, err := s.project(ctx, req.orgID, string(req.Environment))
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch project: %w", err)
+		return "", err
 	}
 	// sec, err := s.Store.fetchSecret(ctx, req.Name, proj.ID)
 	//if err != nil {
@@ -120,14 +254,64 @@ func (s Service) FetchSecret(ctx context.Context, req fetchRequest) (string, err
 	if err != nil {
 		return "", err
 	}
-	location := fmt.Sprintf("secrets/%s", req.Name)
-	data, err := sc.ReadObject(ctx, proj.Bucket, location)
+	obj, err := locate(ctx, sc, proj.Bucket, string(req.Environment), req.Name)
+	if err != nil {
+		return "", fmt.Errorf("error reading object: %w", err)
+	}
+	data, err := readVersion(ctx, sc, proj.Bucket, obj, req.Version)
 	if err != nil {
 		return "", fmt.Errorf("error reading object: %w", err)
 	}
 	return base64.StdEncoding.EncodeToString(data), nil
 }
 
+func (s Service) DeleteSecret(ctx context.Context, req deleteRequest) error {
+	proj, err := s.project(ctx, req.orgID, req.Environment)
+	if err != nil {
+		return err
+	}
+	sc, err := s.getStorageClient(ctx, proj)
+	if err != nil {
+		return err
+	}
+	obj, err := locate(ctx, sc, proj.Bucket, req.Environment, req.Name)
+	if err != nil {
+		return err
+	}
+	if err := deleteAllVersions(ctx, sc, proj.Bucket, obj); err != nil {
+		return err
+	}
+	return s.Store.DeleteSecret(ctx, req.Name, proj.ID)
+}
+
+// RenameSecret moves the secret and its history to the new name, the new
+// name can't be in use.
+func (s Service) RenameSecret(ctx context.Context, req renameRequest) error {
+	proj, err := s.project(ctx, req.orgID, req.Environment)
+	if err != nil {
+		return err
+	}
+	sc, err := s.getStorageClient(ctx, proj)
+	if err != nil {
+		return err
+	}
+	src, err := locate(ctx, sc, proj.Bucket, req.Environment, req.From)
+	if err != nil {
+		return err
+	}
+	_, err = locate(ctx, sc, proj.Bucket, req.Environment, req.To)
+	if err == nil {
+		return fmt.Errorf("%s: %w", req.To, cerr.ErrSecretExists)
+	} else if !errors.Is(err, cerr.ErrNoSecretFound) {
+		return err
+	}
+	dst := secretOf(req.Environment, req.To)
+	if err := moveSecret(ctx, sc, proj.Bucket, src, dst); err != nil {
+		return err
+	}
+	return s.Store.RenameSecret(ctx, req.From, Secret{Name: req.To, Location: dst.location, ProjectID: proj.ID})
+}
+
 func NewService(st Store, pj projFetcher) Service {
 	return Service{Store: st, proj: pj}
 }
@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/scalescape/dolores/server/lib"
+)
+
+type signersResponse struct {
+	Signers []Signer `json:"signers"`
+}
+
+func ListSigners(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req recipientsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "failed to decode request")
+			return
+		}
+		if err := req.valid(); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		data, err := svc.ListSigners(r.Context(), req.Environment)
+		if err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to list signers")
+			return
+		}
+		resp := signersResponse{Signers: data}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to encode result")
+			return
+		}
+	}
+}
@@ -30,6 +30,7 @@ type Key struct {
 	ProjectID   string         `db:"project_id"`
 	Environment string         `db:"environment"`
 	PublicKey   string         `db:"public_key"`
+	SigningKey  sql.NullString `db:"signing_key"`
 	PrivateKey  sql.NullString `db:"private_key"`
 	CreatedAt   time.Time      `db:"created_at"`
 	UpdatedAt   time.Time      `db:"updated_at"`
@@ -52,6 +53,71 @@ func (s Store) ListUsersPublicKeys(ctx context.Context, env, orgID string) ([]Ke
 	return result, nil
 }
 
+func (s Store) ListUsersSigningKeys(ctx context.Context, env, orgID string) ([]Key, error) {
+	query := `select user_id, signing_key from user_keys
+        where user_id in (
+            select id from users where org_id = ?
+        ) and
+        environment = ? and
+        signing_key is not null`
+	query = s.db.Rebind(query)
+	var result []Key
+	if err := s.db.SelectContext(ctx, &result, query, orgID, env); err != nil {
+		return nil, fmt.Errorf("error fetching signing keys for org %s: %w", orgID, err)
+	}
+	return result, nil
+}
+
+func (s Store) ListUsersKeys(ctx context.Context, env, orgID string) ([]Key, error) {
+	query := `select user_id, public_key, signing_key, created_at from user_keys
+        where user_id in (
+            select id from users where org_id = ?
+        ) and
+        environment = ?
+        order by created_at`
+	query = s.db.Rebind(query)
+	var result []Key
+	if err := s.db.SelectContext(ctx, &result, query, orgID, env); err != nil {
+		return nil, fmt.Errorf("error fetching user_keys for org %s: %w", orgID, err)
+	}
+	return result, nil
+}
+
+func (s Store) SaveUserKey(ctx context.Context, orgID string, key Key) error {
+	query := `INSERT into user_keys
+        (user_id, environment, public_key, signing_key, created_at, updated_at)
+        select id, ?, ?, ?, now(), now() from users where id = ? and org_id = ?
+        ON CONFLICT (user_id, environment)
+        DO UPDATE SET public_key = excluded.public_key, signing_key = excluded.signing_key, updated_at = now()
+    `
+	query = s.db.Rebind(query)
+	res, err := s.db.ExecContext(ctx, query, key.Environment, key.PublicKey, key.SigningKey, key.UserID, orgID)
+	if err != nil {
+		return fmt.Errorf("error saving key of %s: %w", key.UserID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("user %s: %w", key.UserID, cerr.ErrNoUserFound)
+	}
+	return nil
+}
+
+func (s Store) DeleteUserKey(ctx context.Context, env, userID, orgID string) error {
+	query := `delete from user_keys
+        where user_id in (
+            select id from users where id = ? and org_id = ?
+        ) and
+        environment = ?`
+	query = s.db.Rebind(query)
+	res, err := s.db.ExecContext(ctx, query, userID, orgID, env)
+	if err != nil {
+		return fmt.Errorf("error deleting key of %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("user %s: %w", userID, cerr.ErrNoUserFound)
+	}
+	return nil
+}
+
 func (s Store) SaveSecret(ctx context.Context, sec Secret) error {
 	query := `INSERT into secrets
         (id, project_id, location, name, created_at, updated_at) values
@@ -66,6 +132,49 @@ func (s Store) SaveSecret(ctx context.Context, sec Secret) error {
 	return nil
 }
 
+// DeleteSecret removes the record of the secret, secrets uploaded before
+// they were recorded have none.
+func (s Store) DeleteSecret(ctx context.Context, name, pid string) error {
+	query := s.db.Rebind(`delete from secrets where name = ? and project_id = ?`)
+	if _, err := s.db.ExecContext(ctx, query, name, pid); err != nil {
+		return fmt.Errorf("error deleting secret %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s Store) RenameSecret(ctx context.Context, from string, sec Secret) error {
+	query := `update secrets set
+        name = ?,
+        location = ?,
+        updated_at = now()
+        where name = ? and
+        project_id = ?`
+	query = s.db.Rebind(query)
+	if _, err := s.db.ExecContext(ctx, query, sec.Name, sec.Location, from, sec.ProjectID); err != nil {
+		return fmt.Errorf("error renaming secret %s: %w", from, err)
+	}
+	return nil
+}
+
+// legacySecrets returns the names of the secrets of the project recorded
+// at their legacy path.
+func (s Store) legacySecrets(ctx context.Context, pid string) ([]string, error) {
+	query := s.db.Rebind(`select name from secrets where project_id = ? and location = ? || name`)
+	var names []string
+	if err := s.db.SelectContext(ctx, &names, query, pid, legacySecretsDir+"/"); err != nil {
+		return nil, fmt.Errorf("error fetching secrets of project %s: %w", pid, err)
+	}
+	return names, nil
+}
+
+func (s Store) updateLocation(ctx context.Context, pid, name, location string) error {
+	query := s.db.Rebind(`update secrets set location = ?, updated_at = now() where name = ? and project_id = ?`)
+	if _, err := s.db.ExecContext(ctx, query, location, name, pid); err != nil {
+		return fmt.Errorf("error updating location of secret %s: %w", name, err)
+	}
+	return nil
+}
+
 func (s Store) fetchSecret(ctx context.Context, name, pid string) (Secret, error) {
 	query := `select * from secrets where
         name = ? and
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud/cld"
 	"github.com/scalescape/dolores/server/lib"
 )
 
@@ -17,8 +18,8 @@ type uploadRequest struct {
 }
 
 func (r *uploadRequest) Valid() error {
-	if r.Environment != "staging" && r.Environment != "production" {
-		return cerr.ErrInvalidEnvironment
+	if err := cld.Environment(r.Environment).Valid(); err != nil {
+		return err
 	}
 	var err error
 	r.decodedData, err = base64.StdEncoding.DecodeString(r.Data)
@@ -46,7 +47,7 @@ func Upload(svc Service) http.HandlerFunc {
 		}
 		err := svc.UploadSecret(r.Context(), *req)
 		if err != nil {
-			lib.WriteError(w, http.StatusInternalServerError, err, "failed to upload secret")
+			lib.WriteError(w, errStatus(err), err, "failed to upload secret")
 			return
 		}
 		var resp uploadResponse
@@ -0,0 +1,271 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scalescape/dolores/server/cerr"
+	"github.com/scalescape/dolores/server/cloud"
+	"github.com/scalescape/dolores/server/lib"
+	"github.com/scalescape/dolores/server/org"
+)
+
+const (
+	historyTimeFormat = "20060102T150405.000Z"
+	currentVersion    = "current"
+	legacySecretsDir  = "secrets"
+)
+
+type SecretVersion struct {
+	Version   string    `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Current   bool      `json:"current"`
+}
+
+type versionsResponse struct {
+	Versions []SecretVersion `json:"versions"`
+}
+
+// secretPath is the object of the secret, every environment has its own
+// prefix as copied environments share the bucket.
+func secretPath(env, name string) string {
+	return path.Join(env, "secrets", name)
+}
+
+func historyPath(env, name string) string {
+	return path.Join(env, "history", name)
+}
+
+// secretObject is where a secret and its history copies are stored.
+type secretObject struct {
+	location string
+	history  string
+}
+
+func secretOf(env, name string) secretObject {
+	return secretObject{location: secretPath(env, name), history: historyPath(env, name)}
+}
+
+// legacySecret is where secrets were stored before the paths had the
+// environment, they're read from there until MigrateLayout moves them.
+func legacySecret(name string) secretObject {
+	return secretObject{location: path.Join(legacySecretsDir, name), history: path.Join("history", name)}
+}
+
+// locate returns the object of the secret, falling back on its legacy path.
+func locate(ctx context.Context, sc cloud.StorageClient, bucket, env, name string) (secretObject, error) {
+	obj := secretOf(env, name)
+	exists, err := sc.ExistsObject(ctx, bucket, obj.location)
+	if err != nil || exists {
+		return obj, err
+	}
+	legacy := legacySecret(name)
+	if exists, err = sc.ExistsObject(ctx, bucket, legacy.location); err != nil {
+		return secretObject{}, err
+	} else if exists {
+		return legacy, nil
+	}
+	return secretObject{}, fmt.Errorf("%s: %w", obj.location, cerr.ErrNoSecretFound)
+}
+
+// versioned reports whether the bucket keeps versions itself, the history
+// copies are used when it can't be checked.
+func versioned(ctx context.Context, sc cloud.StorageClient, bucket string) bool {
+	enabled, err := sc.VersioningEnabled(ctx, bucket)
+	if err != nil {
+		log.Warn().Msgf("failed to check bucket versioning of %s: %v", bucket, err)
+		return false
+	}
+	return enabled
+}
+
+// keepHistory copies the secret under history before it's overwritten.
+func keepHistory(ctx context.Context, sc cloud.StorageClient, bucket string, obj secretObject) error {
+	if versioned(ctx, sc, bucket) {
+		return nil
+	}
+	exists, err := sc.ExistsObject(ctx, bucket, obj.location)
+	if err != nil || !exists {
+		return err
+	}
+	version := time.Now().UTC().Format(historyTimeFormat)
+	if err := sc.CopyObject(ctx, bucket, obj.location, path.Join(obj.history, version)); err != nil {
+		return fmt.Errorf("failed to keep history of %s: %w", obj.location, err)
+	}
+	return nil
+}
+
+func readVersion(ctx context.Context, sc cloud.StorageClient, bucket string, obj secretObject, version string) ([]byte, error) {
+	switch {
+	case version == "" || version == currentVersion:
+		return sc.ReadObject(ctx, bucket, obj.location)
+	case versioned(ctx, sc, bucket):
+		return sc.ReadObjectVersion(ctx, bucket, obj.location, version)
+	default:
+		return sc.ReadObject(ctx, bucket, path.Join(obj.history, version))
+	}
+}
+
+// moveSecret copies the secret with its history before deleting it.
+func moveSecret(ctx context.Context, sc cloud.StorageClient, bucket string, src, dst secretObject) error {
+	if err := copyHistory(ctx, sc, bucket, src, dst); err != nil {
+		return err
+	}
+	if err := sc.CopyObject(ctx, bucket, src.location, dst.location); err != nil {
+		return err
+	}
+	return deleteAllVersions(ctx, sc, bucket, src)
+}
+
+// copyHistory copies the earlier versions of the secret to another object,
+// oldest first so the bucket versions keep their order.
+func copyHistory(ctx context.Context, sc cloud.StorageClient, bucket string, src, dst secretObject) error {
+	if !versioned(ctx, sc, bucket) {
+		history, err := sc.ListObject(ctx, bucket, src.history+"/")
+		if err != nil {
+			return err
+		}
+		for _, o := range history {
+			if err := sc.CopyObject(ctx, bucket, o.Name, path.Join(dst.history, path.Base(o.Name))); err != nil {
+				return fmt.Errorf("failed to copy history of %s: %w", src.location, err)
+			}
+		}
+		return nil
+	}
+	versions, err := sc.ListObjectVersions(ctx, bucket, src.location)
+	if err != nil {
+		return err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Latest {
+			continue
+		}
+		data, err := sc.ReadObjectVersion(ctx, bucket, src.location, versions[i].Version)
+		if err != nil {
+			return err
+		}
+		if err := sc.WriteToObject(ctx, bucket, dst.location, data); err != nil {
+			return fmt.Errorf("failed to copy version %s of %s: %w", versions[i].Version, src.location, err)
+		}
+	}
+	return nil
+}
+
+// deleteAllVersions deletes the secret with its history, the bucket keeps
+// versions of deleted objects so each one is deleted.
+func deleteAllVersions(ctx context.Context, sc cloud.StorageClient, bucket string, obj secretObject) error {
+	if !versioned(ctx, sc, bucket) {
+		history, err := sc.ListObject(ctx, bucket, obj.history+"/")
+		if err != nil {
+			return err
+		}
+		for _, o := range history {
+			if err := sc.DeleteObject(ctx, bucket, o.Name); err != nil {
+				return fmt.Errorf("failed to delete history of %s: %w", obj.location, err)
+			}
+		}
+		return sc.DeleteObject(ctx, bucket, obj.location)
+	}
+	versions, err := sc.ListObjectVersions(ctx, bucket, obj.location)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return !versions[i].Latest && versions[j].Latest })
+	for _, v := range versions {
+		if err := sc.DeleteObjectVersion(ctx, bucket, obj.location, v.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Service) ListSecretVersions(ctx context.Context, req fetchRequest) ([]SecretVersion, error) {
+	proj, err := s.project(ctx, req.orgID, string(req.Environment))
+	if err != nil {
+		return nil, err
+	}
+	sc, err := s.getStorageClient(ctx, proj)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := locate(ctx, sc, proj.Bucket, string(req.Environment), req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if versioned(ctx, sc, proj.Bucket) {
+		objs, err := sc.ListObjectVersions(ctx, proj.Bucket, obj.location)
+		if err != nil {
+			return nil, err
+		}
+		versions := make([]SecretVersion, len(objs))
+		for i, o := range objs {
+			versions[i] = SecretVersion{Version: o.Version, UpdatedAt: o.UpdatedAt, Current: o.Latest}
+		}
+		return versions, nil
+	}
+	versions := make([]SecretVersion, 0)
+	current, err := sc.ListObject(ctx, proj.Bucket, obj.location)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range current {
+		if o.Name == obj.location {
+			versions = append(versions, SecretVersion{Version: currentVersion, UpdatedAt: o.UpdatedAt, Current: true})
+		}
+	}
+	history, err := sc.ListObject(ctx, proj.Bucket, obj.history+"/")
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range history {
+		versions = append(versions, SecretVersion{Version: path.Base(o.Name), UpdatedAt: o.UpdatedAt})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Current != versions[j].Current {
+			return versions[i].Current
+		}
+		return versions[i].Version > versions[j].Version
+	})
+	return versions, nil
+}
+
+func ListVersions(svc Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := fetchRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		ctx := r.Context()
+		var ok bool
+		req.orgID, ok = ctx.Value(org.IDKey).(string)
+		if !ok || req.orgID == "" {
+			lib.WriteError(w, http.StatusBadRequest, cerr.ErrInvalidOrgID, "invalid org ID")
+			return
+		}
+		if err := req.Valid(); err != nil {
+			lib.WriteError(w, http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		versions, err := svc.ListSecretVersions(ctx, req)
+		if errors.Is(err, cerr.ErrNoSecretFound) {
+			lib.WriteError(w, http.StatusNotFound, err, "failed to list secret versions")
+			return
+		}
+		if err != nil {
+			lib.WriteError(w, errStatus(err), err, "failed to list secret versions")
+			return
+		}
+		if err := json.NewEncoder(w).Encode(versionsResponse{Versions: versions}); err != nil {
+			lib.WriteError(w, http.StatusInternalServerError, err, "failed to encode result")
+			return
+		}
+	}
+}
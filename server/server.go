@@ -33,14 +33,30 @@ func server(appCfg config.Application) (*Application, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error creating db conn: %w", err)
 	}
+	if err := migrateSchema(context.Background(), db); err != nil {
+		return nil, err
+	}
 	plStore := platform.NewStore(db, aesKey)
 	plService := platform.NewService(appCfg.DefaultManagedZone, plStore)
 
 	secService := secrets.NewService(secrets.NewStore(db, aesKey), plService)
+	// secrets are read from their legacy paths until they're moved
+	if err := secService.MigrateLayout(context.Background()); err != nil {
+		log.Error().Msgf("[Main] error migrating secrets layout: %v", err)
+	}
 	m.Handle("/secrets/recipients", secrets.ListRecipients(secService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/secrets/signers", secrets.ListSigners(secService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/secrets/keys", secrets.ListKeys(secService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/secrets/keys", secrets.AddKey(secService)).Methods(http.MethodPut, http.MethodOptions)
+	m.Handle("/secrets/keys", secrets.RemoveKey(secService)).Methods(http.MethodDelete, http.MethodOptions)
+	m.Handle("/environments", platform.ListEnvironments(plService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/environments", platform.CreateEnvironment(plService)).Methods(http.MethodPost, http.MethodOptions)
 	m.Handle("/environment/{env}/secrets", secrets.List(secService)).Methods(http.MethodGet, http.MethodOptions)
 	m.Handle("/secrets", secrets.Upload(secService)).Methods(http.MethodPut, http.MethodOptions)
 	m.Handle("/secrets", secrets.Fetch(secService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/secrets", secrets.Delete(secService)).Methods(http.MethodDelete, http.MethodOptions)
+	m.Handle("/secrets/versions", secrets.ListVersions(secService)).Methods(http.MethodGet, http.MethodOptions)
+	m.Handle("/secrets/rename", secrets.Rename(secService)).Methods(http.MethodPost, http.MethodOptions)
 
 	return &Application{router: m}, nil
 }
@@ -0,0 +1,152 @@
+package dolores
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	signingKeyPrefix = "DOLORES-SIGNING-KEY-"
+	signerKeyPrefix  = "dolores-ed25519 "
+	signatureContext = "dolores-envelope-v1\n"
+)
+
+var (
+	ErrUnsigned         = errors.New("config isn't signed")
+	ErrUnknownSigner    = errors.New("config isn't signed by a known team member")
+	ErrInvalidSignature = errors.New("invalid config signature")
+)
+
+// SigningKey signs the envelope of uploaded configs, so readers can check
+// the config was uploaded by a team member.
+type SigningKey struct {
+	key ed25519.PrivateKey
+}
+
+func GenerateSigningKey() (*SigningKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return &SigningKey{key: key}, nil
+}
+
+// Public returns the key published to the team, in the form
+// "dolores-ed25519 <base64>".
+func (k *SigningKey) Public() string {
+	pub, _ := k.key.Public().(ed25519.PublicKey)
+	return signerKeyPrefix + base64.StdEncoding.EncodeToString(pub)
+}
+
+// Bytes returns the content of the signing key file.
+func (k *SigningKey) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# created: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(buf, "# public key: %s\n", k.Public())
+	fmt.Fprintf(buf, "%s%s\n", signingKeyPrefix, base64.RawStdEncoding.EncodeToString(k.key.Seed()))
+	return buf.Bytes()
+}
+
+func (k *SigningKey) sign(env *Envelope) {
+	env.Signer = Fingerprint(k.Public())
+	env.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.key, env.signedMessage()))
+}
+
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, signingKeyPrefix) {
+			continue
+		}
+		seed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(line, signingKeyPrefix))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("malformed signing key: %w", ErrInvalidKeyFile)
+		}
+		return &SigningKey{key: ed25519.NewKeyFromSeed(seed)}, nil
+	}
+	return nil, fmt.Errorf("no signing key found: %w", ErrInvalidKeyFile)
+}
+
+// LoadSigningKey reads the signing key file, unlocking it first when it's
+// protected with a passphrase.
+func LoadSigningKey(fname string, passphrase PassphraseFunc) (*SigningKey, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("error opening signing key %s: %w", fname, err)
+	}
+	if isProtectedKey(data) {
+		if data, err = unlockKey(fname, data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return ParseSigningKey(data)
+}
+
+// Signer is a team member allowed to upload configs.
+type Signer struct {
+	ID        string
+	PublicKey string
+}
+
+func parseSignerKey(key string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(key), signerKeyPrefix))
+	if err != nil || len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed signing public key: %w", ErrInvalidSignature)
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// VerifySignature checks the envelope is signed by one of the signers and
+// that the signer is the recorded author.
+func (e Envelope) VerifySignature(signers []Signer) (Signer, error) {
+	if e.Signature == "" {
+		return Signer{}, ErrUnsigned
+	}
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return Signer{}, fmt.Errorf("malformed signature: %w", ErrInvalidSignature)
+	}
+	for _, s := range signers {
+		if Fingerprint(s.PublicKey) != e.Signer {
+			continue
+		}
+		pub, err := parseSignerKey(s.PublicKey)
+		if err != nil {
+			return Signer{}, err
+		}
+		if !ed25519.Verify(pub, e.signedMessage(), sig) {
+			return Signer{}, ErrInvalidSignature
+		}
+		if e.Author != s.ID {
+			return Signer{}, fmt.Errorf("signed by %s but authored by %s: %w", s.ID, e.Author, ErrInvalidSignature)
+		}
+		return s, nil
+	}
+	return Signer{}, fmt.Errorf("signer %s: %w", e.Signer, ErrUnknownSigner)
+}
+
+func (e Envelope) signedMessage() []byte {
+	return []byte(signatureContext + e.Digest() + "\n" + e.ContentHash + "\n")
+}
+
+// WithSigners refuses configs that aren't signed by one of the signers.
+func WithSigners(signers ...Signer) DecryptOpt {
+	return func(c *DecryptConfig) {
+		c.VerifyEnvelope = func(env *Envelope) error {
+			if env == nil {
+				return ErrUnsigned
+			}
+			_, err := env.VerifySignature(signers)
+			return err
+		}
+	}
+}
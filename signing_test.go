@@ -0,0 +1,111 @@
+package dolores
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptSigned(t *testing.T, key *SigningKey) []byte {
+	t.Helper()
+	enc, err := NewEncryptor(alicePubKey)
+	require.NoError(t, err)
+	enc.Author = "alice"
+	enc.SigningKey = key
+	buf := new(bytes.Buffer)
+	require.NoError(t, enc.EncryptTo(buf, strings.NewReader("KEY=value\n")))
+	return buf.Bytes()
+}
+
+func TestShouldLoadSavedSigningKey(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	fname := filepath.Join(t.TempDir(), "staging.sign")
+	require.NoError(t, os.WriteFile(fname, key.Bytes(), 0o600))
+
+	loaded, err := LoadSigningKey(fname, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, key.Public(), loaded.Public())
+	assert.True(t, strings.HasPrefix(key.Public(), "dolores-ed25519 "))
+}
+
+func TestShouldVerifySignedConfig(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	other, err := GenerateSigningKey()
+	require.NoError(t, err)
+	alice := Signer{ID: "alice", PublicKey: key.Public()}
+	tests := map[string]struct {
+		signers []Signer
+		data    func([]byte) []byte
+		err     error
+	}{
+		"known signer":   {signers: []Signer{alice}},
+		"unknown signer": {signers: []Signer{{ID: "alice", PublicKey: other.Public()}}, err: ErrUnknownSigner},
+		"other author":   {signers: []Signer{{ID: "bob", PublicKey: key.Public()}}, err: ErrInvalidSignature},
+		"tampered": {signers: []Signer{alice}, err: ErrInvalidSignature, data: func(d []byte) []byte {
+			return bytes.Replace(d, []byte("content_hash: sha256:"), []byte("content_hash: sha256:00"), 1)
+		}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := encryptSigned(t, key)
+			if tc.data != nil {
+				data = tc.data(data)
+			}
+			env, _, err := ReadEnvelope(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			signer, err := env.VerifySignature(tc.signers)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, alice, signer)
+		})
+	}
+}
+
+func TestShouldRefuseUnsignedConfigWithSigners(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: aliceKeyFile}, WithSigners(Signer{ID: "alice", PublicKey: key.Public()}))
+	require.NoError(t, err)
+
+	_, err = dec.Decrypt(encryptSigned(t, nil))
+	assert.ErrorIs(t, err, ErrUnsigned)
+
+	result, err := dec.Decrypt(encryptSigned(t, key))
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", string(result))
+}
+
+func TestShouldKeepSignatureWhenMarkingRotation(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	env, payload, err := ReadEnvelope(bytes.NewReader(encryptSigned(t, key)))
+	require.NoError(t, err)
+	env.NeedsRotation = []string{"DB_PASSWORD"}
+	rest, err := io.ReadAll(payload)
+	require.NoError(t, err)
+	marked := append(env.Bytes(), rest...)
+
+	dec, err := NewDecryptor(&DecryptConfig{KeyFile: aliceKeyFile}, WithSigners(Signer{ID: "alice", PublicKey: key.Public()}))
+	require.NoError(t, err)
+	result, err := dec.Decrypt(marked)
+
+	require.NoError(t, err)
+	assert.Equal(t, "KEY=value\n", string(result))
+	parsed, _, err := ReadEnvelope(bytes.NewReader(marked))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD"}, parsed.NeedsRotation)
+}
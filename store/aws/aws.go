@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/rs/zerolog/log"
@@ -102,6 +104,41 @@ func (s StorageClient) WriteToObject(ctx context.Context, bucketName, fileName s
 	return nil
 }
 
+// WriteObjectStream uploads from the reader in parts, so the object never
+// has to be held in memory.
+func (s StorageClient) WriteObjectStream(ctx context.Context, bucketName, fileName string, r io.Reader) error {
+	log.Debug().Msgf("streaming to %s/%s", bucketName, fileName)
+	bucketExist, err := s.bucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch bucket: %w", err)
+	}
+	if !bucketExist {
+		if err := s.CreateBucket(ctx, bucketName); err != nil {
+			return err
+		}
+	}
+	_, err = manager.NewUploader(s.client).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+		Body:   r,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload secret: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) ReadObjectStream(ctx context.Context, bucketName, fileName string) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object : %w", err)
+	}
+	return resp.Body, nil
+}
+
 func (s StorageClient) ReadObject(ctx context.Context, bucketName, fileName string) ([]byte, error) {
 	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
@@ -134,6 +171,93 @@ func (s StorageClient) ExistsObject(ctx context.Context, bucketName, fileName st
 	return true, nil
 }
 
+func (s StorageClient) DeleteObject(ctx context.Context, bucketName, fileName string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// CopyObject copies the object within the bucket on the server side.
+func (s StorageClient) CopyObject(ctx context.Context, bucketName, src, dst string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucketName),
+		CopySource: aws.String(bucketName + "/" + url.PathEscape(src)),
+		Key:        aws.String(dst),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) VersioningEnabled(ctx context.Context, bucketName string) (bool, error) {
+	resp, err := s.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return false, fmt.Errorf("failed to get bucket versioning: %w", err)
+	}
+	return resp.Status == types.BucketVersioningStatusEnabled, nil
+}
+
+// ListObjectVersions returns the versions of the object, newest first. The
+// listing is paged by key and version markers.
+func (s StorageClient) ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cloud.ObjectVersion, error) {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(fileName),
+	}
+	versions := make([]cloud.ObjectVersion, 0)
+	for {
+		resp, err := s.client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list object versions: %w", err)
+		}
+		for _, v := range resp.Versions {
+			if aws.ToString(v.Key) != fileName {
+				continue
+			}
+			versions = append(versions, cloud.ObjectVersion{
+				Name: fileName, Version: aws.ToString(v.VersionId),
+				Updated: aws.ToTime(v.LastModified), Latest: v.IsLatest,
+			})
+		}
+		if !resp.IsTruncated {
+			return versions, nil
+		}
+		input.KeyMarker, input.VersionIdMarker = resp.NextKeyMarker, resp.NextVersionIdMarker
+	}
+}
+
+// DeleteObjectVersion removes the version for good, deleting the object
+// only adds a delete marker in versioned buckets.
+func (s StorageClient) DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(fileName),
+		VersionId: aws.String(version),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object version %s: %w", version, err)
+	}
+	return nil
+}
+
+func (s StorageClient) ReadObjectVersionStream(ctx context.Context, bucketName, fileName, version string) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(fileName),
+		VersionId: aws.String(version),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object version %s: %w", version, err)
+	}
+	return resp.Body, nil
+}
+
 func NewStore(ctx context.Context, acfg Config) (StorageClient, error) {
 	cp := config.WithSharedCredentialsFiles([]string{acfg.Credentials})
 	cfg, err := config.LoadDefaultConfig(ctx, cp)
@@ -8,3 +8,11 @@ type Object struct {
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
 }
+
+// ObjectVersion is a version kept by a bucket with versioning enabled.
+type ObjectVersion struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	Updated time.Time `json:"updated"`
+	Latest  bool      `json:"latest"`
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 
 	"cloud.google.com/go/storage"
 	"github.com/rs/zerolog/log"
@@ -74,6 +76,51 @@ func (s StorageClient) WriteToObject(ctx context.Context, bucketName, fileName s
 	return nil
 }
 
+func (s StorageClient) WriteObjectStream(ctx context.Context, bucketName, fileName string, r io.Reader) error {
+	log.Debug().Msgf("streaming to %s/%s", bucketName, fileName)
+	bucket := s.Client.Bucket(bucketName)
+	_, err := bucket.Attrs(ctx)
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		if err := s.createNewBucket(ctx, bucketName); err != nil {
+			return fmt.Errorf("error creating new bucket: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to check bucket: %w", err)
+	}
+	return writeStream(ctx, func(ctx context.Context) io.WriteCloser {
+		return bucket.Object(fileName).NewWriter(ctx)
+	}, r)
+}
+
+// writeStream commits the upload only when the whole reader was copied,
+// closing the writer would commit a partial object so a failed copy cancels
+// its context instead.
+func writeStream(ctx context.Context, open func(context.Context) io.WriteCloser, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := open(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		cancel()
+		return fmt.Errorf("error writing data to file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error finishing upload: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) ReadObjectStream(ctx context.Context, bucketName, fileName string) (io.ReadCloser, error) {
+	obj, err := s.getObject(ctx, bucketName, fileName)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	return rdr, nil
+}
+
 func (s StorageClient) ReadObject(ctx context.Context, bucketName, fileName string) ([]byte, error) {
 	obj, err := s.getObject(ctx, bucketName, fileName)
 	if err != nil {
@@ -139,6 +186,86 @@ func (s StorageClient) ExistsObject(ctx context.Context, bucket, fileName string
 	return true, nil
 }
 
+func (s StorageClient) DeleteObject(ctx context.Context, bucketName, fileName string) error {
+	obj, err := s.getObject(ctx, bucketName, fileName)
+	if err != nil {
+		return err
+	}
+	if err := obj.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// CopyObject copies the object within the bucket on the server side.
+func (s StorageClient) CopyObject(ctx context.Context, bucketName, src, dst string) error {
+	obj, err := s.getObject(ctx, bucketName, src)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Client.Bucket(bucketName).Object(dst).CopierFrom(obj).Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+func (s StorageClient) VersioningEnabled(ctx context.Context, bucketName string) (bool, error) {
+	attrs, err := s.Client.Bucket(bucketName).Attrs(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get bucket: %w", err)
+	}
+	return attrs.VersioningEnabled, nil
+}
+
+// ListObjectVersions returns the generations of the object, newest first.
+func (s StorageClient) ListObjectVersions(ctx context.Context, bucketName, fileName string) ([]cloud.ObjectVersion, error) {
+	iter := s.Client.Bucket(bucketName).Objects(ctx, &storage.Query{Prefix: fileName, Versions: true})
+	versions := make([]cloud.ObjectVersion, 0)
+	for {
+		attrs, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate object versions: %w", err)
+		}
+		if attrs.Name != fileName {
+			continue
+		}
+		versions = append(versions, cloud.ObjectVersion{
+			Name: fileName, Version: strconv.FormatInt(attrs.Generation, 10),
+			Updated: attrs.Updated, Latest: attrs.Deleted.IsZero(),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Updated.After(versions[j].Updated) })
+	return versions, nil
+}
+
+func (s StorageClient) ReadObjectVersionStream(ctx context.Context, bucketName, fileName, version string) (io.ReadCloser, error) {
+	gen, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation %s: %w", version, err)
+	}
+	rdr, err := s.Client.Bucket(bucketName).Object(fileName).Generation(gen).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object generation %s: %w", version, err)
+	}
+	return rdr, nil
+}
+
+// DeleteObjectVersion removes the generation for good, deleting the object
+// only makes it noncurrent in versioned buckets.
+func (s StorageClient) DeleteObjectVersion(ctx context.Context, bucketName, fileName, version string) error {
+	gen, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid generation %s: %w", version, err)
+	}
+	if err := s.Client.Bucket(bucketName).Object(fileName).Generation(gen).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete object generation %s: %w", version, err)
+	}
+	return nil
+}
+
 func (s StorageClient) getObject(ctx context.Context, bucketName, fileName string) (*storage.ObjectHandle, error) {
 	bucket := s.Client.Bucket(bucketName)
 	if _, err := bucket.Attrs(ctx); err != nil {
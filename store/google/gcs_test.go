@@ -0,0 +1,75 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSourceFailed = errors.New("source failed")
+
+// fakeWriter commits on Close unless its context was cancelled, like the
+// GCS object writer.
+type fakeWriter struct {
+	ctx       context.Context
+	buf       bytes.Buffer
+	committed bool
+}
+
+func (w *fakeWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *fakeWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	w.committed = true
+	return nil
+}
+
+type failingReader struct{ r io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errSourceFailed
+	}
+	return n, err
+}
+
+func TestShouldNotCommitPartialStream(t *testing.T) {
+	w := new(fakeWriter)
+	open := func(ctx context.Context) io.WriteCloser {
+		w.ctx = ctx
+		return w
+	}
+
+	err := writeStream(context.Background(), open, failingReader{strings.NewReader("partial")})
+
+	require.ErrorIs(t, err, errSourceFailed)
+	assert.False(t, w.committed)
+	assert.Error(t, w.ctx.Err(), "upload context should be cancelled")
+}
+
+func TestShouldCommitCompleteStream(t *testing.T) {
+	w := new(fakeWriter)
+	open := func(ctx context.Context) io.WriteCloser {
+		w.ctx = ctx
+		return w
+	}
+
+	require.NoError(t, writeStream(context.Background(), open, strings.NewReader("complete")))
+
+	assert.True(t, w.committed)
+	assert.Equal(t, "complete", w.buf.String())
+}
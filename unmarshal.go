@@ -0,0 +1,203 @@
+package dolores
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tagName = "dolores"
+
+var (
+	ErrInvalidTarget  = errors.New("target must be a non-nil pointer to a struct")
+	ErrMissingKey     = errors.New("required key missing")
+	ErrMalformedValue = errors.New("malformed value")
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type fieldTag struct {
+	name       string
+	required   bool
+	defaultVal *string
+	skip       bool
+}
+
+func parseTag(field reflect.StructField) fieldTag {
+	tag := fieldTag{name: FlatKey(field.Name)}
+	value, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return tag
+	}
+	if value == "-" {
+		return fieldTag{skip: true}
+	}
+	parts := strings.Split(value, ",")
+	if parts[0] != "" {
+		tag.name = parts[0]
+	}
+	for i := 1; i < len(parts); i++ {
+		opt := parts[i]
+		switch {
+		case opt == "required":
+			tag.required = true
+		case strings.HasPrefix(opt, "default="):
+			// defaults may contain commas, e.g. a list of hosts
+			def := strings.Join(append([]string{strings.TrimPrefix(opt, "default=")}, parts[i+1:]...), ",")
+			tag.defaultVal = &def
+			i = len(parts)
+		}
+	}
+	return tag
+}
+
+// Unmarshal fills the struct pointed to by v from the variables, using the
+// `dolores:"NAME,required,default=value"` field tags. Untagged fields are
+// looked up by their upper-cased name and nested structs by NAME__FIELD.
+// Every missing or malformed key is reported in the returned error.
+func Unmarshal(vars []Variable, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	values := make(map[string]string, len(vars))
+	for _, vr := range vars {
+		values[string(vr.Key)] = string(vr.Value)
+	}
+	var errs []error
+	unmarshalStruct(rv.Elem(), "", values, &errs)
+	return errors.Join(errs...)
+}
+
+func unmarshalStruct(rv reflect.Value, prefix string, values map[string]string, errs *[]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := parseTag(field)
+		if tag.skip {
+			continue
+		}
+		key := prefix + tag.name
+		fv := rv.Field(i)
+		if isNestedStruct(field.Type) {
+			unmarshalStruct(fv, key+KeySeparator, values, errs)
+			continue
+		}
+		raw, ok := values[key]
+		if !ok && tag.defaultVal != nil {
+			raw, ok = *tag.defaultVal, true
+		}
+		if !ok {
+			if tag.required {
+				*errs = append(*errs, fmt.Errorf("%s (%s): %w", key, field.Name, ErrMissingKey))
+			}
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s (%s): %w: %w", key, field.Name, ErrMalformedValue, err))
+		}
+	}
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == urlType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// revive:disable cyclomatic
+func setValue(fv reflect.Value, raw string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)) //nolint:forcetypeassert
+	}
+	switch {
+	case fv.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case fv.Type() == urlType:
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	return setKind(fv, raw)
+}
+
+func setKind(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		return setSlice(fv, raw)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func setSlice(fv reflect.Value, raw string) error {
+	if raw == "" {
+		fv.Set(reflect.MakeSlice(fv.Type(), 0, 0))
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		if err := setValue(slice.Index(i), strings.TrimSpace(p)); err != nil {
+			return err
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
@@ -0,0 +1,69 @@
+package dolores
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dbConfig struct {
+	Host string
+	Port int `dolores:",default=5432"`
+}
+
+type appConfig struct {
+	DatabaseURL url.URL       `dolores:"DB_URL,required"`
+	Debug       bool          `dolores:"DEBUG"`
+	Timeout     time.Duration `dolores:"TIMEOUT,default=5s"`
+	Hosts       []string      `dolores:"HOSTS,default=a,b"`
+	Retries     *int          `dolores:"RETRIES"`
+	DB          dbConfig
+	Ignored     string `dolores:"-"`
+}
+
+func TestShouldUnmarshalVariablesIntoStruct(t *testing.T) {
+	vars := []Variable{
+		{[]byte("DB_URL"), []byte("postgres://u:p@h/db")},
+		{[]byte("DEBUG"), []byte("true")},
+		{[]byte("RETRIES"), []byte("3")},
+		{[]byte("DB__HOST"), []byte("localhost")},
+		{[]byte("IGNORED"), []byte("value")},
+	}
+	var cfg appConfig
+
+	err := Unmarshal(vars, &cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "h", cfg.DatabaseURL.Host)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+	require.NotNil(t, cfg.Retries)
+	assert.Equal(t, 3, *cfg.Retries)
+	assert.Equal(t, dbConfig{Host: "localhost", Port: 5432}, cfg.DB)
+	assert.Empty(t, cfg.Ignored)
+}
+
+func TestShouldReportEveryMissingAndMalformedKey(t *testing.T) {
+	vars := []Variable{
+		{[]byte("DEBUG"), []byte("maybe")},
+		{[]byte("DB__PORT"), []byte("http")},
+	}
+	var cfg appConfig
+
+	err := Unmarshal(vars, &cfg)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrMissingKey)
+	assert.ErrorIs(t, err, ErrMalformedValue)
+	assert.Contains(t, err.Error(), "DB_URL")
+	assert.Contains(t, err.Error(), "DEBUG")
+	assert.Contains(t, err.Error(), "DB__PORT")
+}
+
+func TestShouldRejectNonPointerTarget(t *testing.T) {
+	assert.ErrorIs(t, Unmarshal(nil, appConfig{}), ErrInvalidTarget)
+}
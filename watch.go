@@ -0,0 +1,242 @@
+package dolores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/scalescape/dolores/client"
+	"github.com/scalescape/dolores/lib"
+)
+
+var (
+	ErrConfigNotFound   = errors.New("config not found")
+	ErrListNotSupported = errors.New("fetcher can't list configs")
+	ErrInvalidInterval  = errors.New("watch interval must be positive")
+	ErrWatcherStarted   = errors.New("watcher already started")
+)
+
+const (
+	defaultWatchInterval = 30 * time.Second
+	defaultMaxBackoff    = 5 * time.Minute
+)
+
+// Change is delivered whenever the watched config is uploaded with different
+// variables. The first successful poll reports every variable as added.
+type Change struct {
+	Diff
+	Values    Values
+	UpdatedAt time.Time
+}
+
+type Watcher struct {
+	env        string
+	name       string
+	loadOpts   []LoadOpt
+	interval   time.Duration
+	maxBackoff time.Duration
+	onChange   func(Change)
+	onError    func(error)
+	changes    chan Change
+	start      sync.Once
+
+	mu        sync.RWMutex
+	loaded    bool
+	vars      []Variable
+	updatedAt time.Time
+}
+
+type WatchOpt func(w *Watcher)
+
+// WithInterval sets how often the backend is polled for updates, Run
+// refuses an interval that isn't positive.
+func WithInterval(d time.Duration) WatchOpt {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// WithMaxBackoff caps the wait between polls after consecutive failures.
+func WithMaxBackoff(d time.Duration) WatchOpt {
+	return func(w *Watcher) {
+		w.maxBackoff = d
+	}
+}
+
+// WithLoadOpts configures decryption and the backend the same way as Load.
+// A custom fetcher must also implement SecretLister.
+func WithLoadOpts(opts ...LoadOpt) WatchOpt {
+	return func(w *Watcher) {
+		w.loadOpts = append(w.loadOpts, opts...)
+	}
+}
+
+// OnChange delivers changes to the callback instead of the Changes channel.
+func OnChange(fn func(Change)) WatchOpt {
+	return func(w *Watcher) {
+		w.onChange = fn
+	}
+}
+
+// OnError is called with every failed poll, before backing off.
+func OnError(fn func(error)) WatchOpt {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+func NewWatcher(env, name string, opts ...WatchOpt) *Watcher {
+	w := &Watcher{
+		env:        env,
+		name:       name,
+		interval:   defaultWatchInterval,
+		maxBackoff: defaultMaxBackoff,
+		changes:    make(chan Change, 1),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.maxBackoff < w.interval {
+		w.maxBackoff = w.interval
+	}
+	return w
+}
+
+// Changes returns the channel changes are sent on when no OnChange callback
+// is configured. It is closed once Run returns.
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
+
+// Values returns the variables decrypted on the last successful poll.
+func (w *Watcher) Values() Values {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return valuesOf(w.vars)
+}
+
+// revive:disable cyclomatic
+
+// Run polls the backend until the context is cancelled. Backend errors are
+// reported to OnError and retried with a jittered exponential backoff. A
+// watcher runs once, the Changes channel is closed when it returns.
+func (w *Watcher) Run(ctx context.Context) error {
+	started := false
+	w.start.Do(func() { started = true })
+	if !started {
+		return ErrWatcherStarted
+	}
+	defer close(w.changes)
+	if w.interval <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidInterval, w.interval)
+	}
+	cfg := newLoadConfig(w.env, w.loadOpts...)
+	if cfg.Fetcher == nil {
+		f, err := newBackend(ctx, w.env)
+		if err != nil {
+			return err
+		}
+		cfg.Fetcher = f
+	}
+	if cfg.Passphrase == nil {
+		cfg.Passphrase = lib.ReadPassphrase
+	}
+	cfg.Passphrase = RememberPassphrase(cfg.Passphrase)
+	lister, ok := cfg.Fetcher.(SecretLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	if err := cfg.verify(w.env); err != nil {
+		return err
+	}
+	dec, err := NewDecryptor(&cfg.DecryptConfig)
+	if err != nil {
+		return err
+	}
+	failures := 0
+	for {
+		wait := w.interval
+		if err := w.poll(ctx, lister, dec); err != nil {
+			if w.onError != nil {
+				w.onError(err)
+			}
+			failures++
+			wait = w.backoff(failures)
+		} else {
+			failures = 0
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context, lister SecretLister, dec Decryptor) error {
+	obj, err := w.lookup(lister)
+	if err != nil {
+		return err
+	}
+	w.mu.RLock()
+	unchanged := w.loaded && obj.UpdatedAt.Equal(w.updatedAt)
+	w.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	ef, err := fetchFile(lister, dec, w.env, w.name)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	first := !w.loaded
+	diff := DiffVariables(w.vars, ef.Variables)
+	w.vars, w.updatedAt, w.loaded = ef.Variables, obj.UpdatedAt, true
+	w.mu.Unlock()
+	if !first && diff.Empty() {
+		return nil
+	}
+	w.deliver(ctx, Change{Diff: diff, Values: valuesOf(ef.Variables), UpdatedAt: obj.UpdatedAt})
+	return nil
+}
+
+func (w *Watcher) lookup(lister SecretLister) (client.SecretObject, error) {
+	objs, err := lister.GetSecretList(client.SecretListConfig{Environment: w.env})
+	if err != nil {
+		return client.SecretObject{}, fmt.Errorf("failed to list configs: %w", err)
+	}
+	for _, obj := range objs {
+		if obj.Name == w.name || obj.BaseName() == w.name {
+			return obj, nil
+		}
+	}
+	return client.SecretObject{}, fmt.Errorf("%w: %s", ErrConfigNotFound, w.name)
+}
+
+func (w *Watcher) deliver(ctx context.Context, c Change) {
+	if w.onChange != nil {
+		w.onChange(c)
+		return
+	}
+	select {
+	case w.changes <- c:
+	case <-ctx.Done():
+	}
+}
+
+// backoff doubles the interval for every consecutive failure up to the max
+// and picks a random wait in the upper half so clients don't poll in sync.
+func (w *Watcher) backoff(failures int) time.Duration {
+	d := w.interval
+	for i := 0; i < failures && d < w.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > w.maxBackoff {
+		d = w.maxBackoff
+	}
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1)) //nolint:gosec
+}
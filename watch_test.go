@@ -0,0 +1,150 @@
+package dolores
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scalescape/dolores/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubLister struct {
+	mu        sync.Mutex
+	data      []byte
+	updatedAt time.Time
+	listErr   error
+	fetches   int
+}
+
+func (s *stubLister) FetchSecrets(_ client.FetchSecretRequest) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	return s.data, nil
+}
+
+func (s *stubLister) GetSecretList(_ client.SecretListConfig) ([]client.SecretObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	return []client.SecretObject{
+		{Name: "secrets/other", UpdatedAt: time.Now()},
+		{Name: "secrets/backend", UpdatedAt: s.updatedAt},
+	}, nil
+}
+
+func (s *stubLister) upload(t *testing.T, vars []Variable, at time.Time) {
+	t.Helper()
+	enc, err := NewEncryptor(alicePubKey)
+	require.NoError(t, err)
+	data, err := enc.Encrypt(vars)
+	require.NoError(t, err)
+	s.mu.Lock()
+	s.data, s.updatedAt = data, at
+	s.mu.Unlock()
+}
+
+type signedLister struct {
+	*stubLister
+	signers []client.Signer
+}
+
+func (s signedLister) GetKeyring(_ string) ([]byte, error) {
+	return nil, client.ErrMethodUndefined
+}
+
+func (s signedLister) GetSigners(_ string) ([]client.Signer, error) {
+	return s.signers, nil
+}
+
+func TestShouldDiffVariablesByKey(t *testing.T) {
+	before := []Variable{{[]byte("A"), []byte("1")}, {[]byte("B"), []byte("2")}, {[]byte("C"), []byte("3")}}
+	after := []Variable{{[]byte("A"), []byte("1")}, {[]byte("C"), []byte("4")}, {[]byte("D"), []byte("5")}}
+
+	diff := DiffVariables(before, after)
+
+	assert.Equal(t, Diff{Added: []string{"D"}, Removed: []string{"B"}, Changed: []string{"C"}}, diff)
+	assert.True(t, DiffVariables(after, after).Empty())
+}
+
+func TestWatcherShouldDeliverChangesOnlyWhenConfigIsUpdated(t *testing.T) {
+	lister := new(stubLister)
+	start := time.Now()
+	lister.upload(t, []Variable{{[]byte("A"), []byte("1")}, {[]byte("B"), []byte("2")}}, start)
+	w := NewWatcher("staging", "backend", WithInterval(time.Millisecond),
+		WithLoadOpts(WithFetcher(lister), WithDecryptOpts(WithKeyFile(aliceKeyFile)), WithAllowUnsigned()))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	first := <-w.Changes()
+	assert.Equal(t, []string{"A", "B"}, first.Added)
+	assert.Equal(t, Values{"A": "1", "B": "2"}, first.Values)
+
+	lister.upload(t, []Variable{{[]byte("A"), []byte("3")}, {[]byte("C"), []byte("4")}}, start.Add(time.Minute))
+	second := <-w.Changes()
+	assert.Equal(t, Diff{Added: []string{"C"}, Removed: []string{"B"}, Changed: []string{"A"}}, second.Diff)
+	assert.Equal(t, Values{"A": "3", "C": "4"}, w.Values())
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 2, lister.fetches)
+}
+
+func TestWatcherShouldReportBackendErrorsAndBackoff(t *testing.T) {
+	lister := &stubLister{listErr: errors.New("unavailable")}
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	w := NewWatcher("staging", "backend", WithInterval(time.Millisecond), WithMaxBackoff(4*time.Millisecond),
+		WithLoadOpts(WithFetcher(lister), WithDecryptOpts(WithKeyFile(aliceKeyFile)), WithAllowUnsigned()),
+		OnError(func(err error) {
+			cancel()
+			errs <- err
+		}))
+
+	require.ErrorIs(t, w.Run(ctx), context.Canceled)
+	assert.ErrorContains(t, <-errs, "unavailable")
+	for i := 1; i < 10; i++ {
+		d := w.backoff(i)
+		assert.GreaterOrEqual(t, d, time.Millisecond/2)
+		assert.LessOrEqual(t, d, 4*time.Millisecond)
+	}
+}
+
+func TestWatcherShouldRejectUnverifiedConfig(t *testing.T) {
+	key, err := GenerateSigningKey()
+	require.NoError(t, err)
+	lister := new(stubLister)
+	lister.upload(t, []Variable{{[]byte("A"), []byte("1")}}, time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	w := NewWatcher("staging", "backend", WithInterval(time.Millisecond),
+		WithLoadOpts(WithFetcher(signedLister{lister, []client.Signer{{UserID: "alice", PublicKey: key.Public()}}}),
+			WithDecryptOpts(WithKeyFile(aliceKeyFile))),
+		OnError(func(err error) {
+			cancel()
+			errs <- err
+		}))
+
+	require.ErrorIs(t, w.Run(ctx), context.Canceled)
+	err = <-errs
+	assert.ErrorIs(t, err, ErrSignatureRequired)
+	assert.ErrorIs(t, err, ErrUnsigned)
+	assert.Empty(t, w.Values())
+}
+
+func TestWatcherShouldRejectInvalidIntervalAndSecondRun(t *testing.T) {
+	lister := &stubLister{}
+	w := NewWatcher("staging", "backend", WithInterval(-time.Second), WithLoadOpts(WithFetcher(lister)))
+
+	require.ErrorIs(t, w.Run(context.Background()), ErrInvalidInterval)
+	require.ErrorIs(t, w.Run(context.Background()), ErrWatcherStarted)
+	_, open := <-w.Changes()
+	assert.False(t, open)
+}